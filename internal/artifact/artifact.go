@@ -0,0 +1,212 @@
+// Package artifact stores oversized tool outputs on disk so the model can
+// page through or search them instead of receiving them in full
+package artifact
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrNotFound is returned when no stored artifact has the given ID
+var ErrNotFound = errors.New("artifact not found")
+
+// validID guards against IDs that would escape the store directory
+var validID = regexp.MustCompile(`^[0-9a-z-]+$`)
+
+// Artifact describes a stored tool output
+type Artifact struct {
+	ID    string
+	Path  string
+	Size  int // Bytes
+	Lines int
+}
+
+// Page is a range of lines read from an artifact
+type Page struct {
+	Offset     int      // 1-based number of the first line returned
+	Lines      []string // The lines, without trailing newlines
+	TotalLines int
+}
+
+// Match is a line that matched a Grep pattern
+type Match struct {
+	Line int // 1-based line number
+	Text string
+}
+
+// Store keeps artifacts as one text file each in a directory
+type Store struct {
+	dir string
+}
+
+// NewStore creates a store rooted at dir; the directory is created on first save
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// DefaultDir returns ~/.cnb-assistant/artifacts
+func DefaultDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate home directory: %w", err)
+	}
+	return filepath.Join(home, ".cnb-assistant", "artifacts"), nil
+}
+
+// Dir returns the directory holding the artifact files
+func (st *Store) Dir() string {
+	return st.dir
+}
+
+// Save writes content to a new artifact. tool only makes the ID easier to recognise.
+func (st *Store) Save(tool, content string) (*Artifact, error) {
+	if err := os.MkdirAll(st.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+
+	id := newID(time.Now(), tool)
+	path := st.path(id)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		return nil, fmt.Errorf("failed to save artifact: %w", err)
+	}
+
+	return &Artifact{
+		ID:    id,
+		Path:  path,
+		Size:  len(content),
+		Lines: len(splitLines(content)),
+	}, nil
+}
+
+// ReadLines returns up to limit lines starting at the 1-based line offset
+func (st *Store) ReadLines(id string, offset, limit int) (*Page, error) {
+	lines, err := st.lines(id)
+	if err != nil {
+		return nil, err
+	}
+	if offset < 1 {
+		offset = 1
+	}
+	if limit < 1 {
+		limit = 1
+	}
+
+	page := &Page{Offset: offset, TotalLines: len(lines)}
+	if offset > len(lines) {
+		return page, nil
+	}
+	end := offset - 1 + limit
+	if end > len(lines) {
+		end = len(lines)
+	}
+	page.Lines = lines[offset-1 : end]
+	return page, nil
+}
+
+// Grep returns up to limit lines matching the regular expression pattern
+func (st *Store) Grep(id, pattern string, limit int) ([]Match, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	lines, err := st.lines(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Match
+	for i, line := range lines {
+		if !re.MatchString(line) {
+			continue
+		}
+		matches = append(matches, Match{Line: i + 1, Text: line})
+		if len(matches) >= limit {
+			break
+		}
+	}
+	return matches, nil
+}
+
+// lines reads an artifact and splits it into lines
+func (st *Store) lines(id string) ([]string, error) {
+	if !validID.MatchString(id) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	data, err := os.ReadFile(st.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact: %w", err)
+	}
+	return splitLines(string(data)), nil
+}
+
+// path returns the file holding the artifact with the given ID
+func (st *Store) path(id string) string {
+	return filepath.Join(st.dir, id+".txt")
+}
+
+// HeadTail returns roughly the first and last n bytes of content, cut at line
+// boundaries where possible and never inside a UTF-8 character
+func HeadTail(content string, n int) (head, tail string) {
+	if len(content) <= 2*n {
+		return content, ""
+	}
+
+	cut := n
+	for cut > 0 && !utf8.RuneStart(content[cut]) {
+		cut--
+	}
+	head = content[:cut]
+	if i := strings.LastIndexByte(head, '\n'); i > 0 {
+		head = head[:i]
+	}
+
+	cut = len(content) - n
+	for cut < len(content) && !utf8.RuneStart(content[cut]) {
+		cut++
+	}
+	tail = content[cut:]
+	if i := strings.IndexByte(tail, '\n'); i >= 0 && i < len(tail)-1 {
+		tail = tail[i+1:]
+	}
+	return head, tail
+}
+
+// splitLines splits s into lines, ignoring a trailing newline
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// newID builds a sortable ID such as 20250101-150405-cnb-getbuildlogs-a1b2c3
+func newID(now time.Time, tool string) string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+
+	var name strings.Builder
+	for _, r := range strings.ToLower(tool) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			name.WriteRune(r)
+		case name.Len() > 0 && !strings.HasSuffix(name.String(), "-"):
+			name.WriteByte('-')
+		}
+	}
+	label := strings.Trim(name.String(), "-")
+	if label == "" {
+		label = "output"
+	}
+	return now.Format("20060102-150405") + "-" + label + "-" + hex.EncodeToString(suffix)
+}
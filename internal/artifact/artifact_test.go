@@ -0,0 +1,84 @@
+package artifact
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestStoreSaveReadGrep(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	var content strings.Builder
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&content, "step %d ok\n", i)
+	}
+	content.WriteString("ERROR: build failed\n")
+
+	a, err := store.Save("cnb_getBuildLogs", content.String())
+	if err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if a.Lines != 101 || a.Size != content.Len() {
+		t.Errorf("Expected 101 lines and %d bytes, got %d lines and %d bytes", content.Len(), a.Lines, a.Size)
+	}
+	if !strings.Contains(a.ID, "cnb-getbuildlogs") {
+		t.Errorf("Expected the ID to name the tool, got %q", a.ID)
+	}
+
+	page, err := store.ReadLines(a.ID, 99, 10)
+	if err != nil {
+		t.Fatalf("ReadLines() failed: %v", err)
+	}
+	if page.TotalLines != 101 || len(page.Lines) != 3 || page.Lines[2] != "ERROR: build failed" {
+		t.Errorf("Unexpected page: %+v", page)
+	}
+
+	matches, err := store.Grep(a.ID, `^ERROR`, 5)
+	if err != nil {
+		t.Fatalf("Grep() failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Line != 101 {
+		t.Errorf("Expected one match on line 101, got %+v", matches)
+	}
+
+	matches, err = store.Grep(a.ID, `ok$`, 5)
+	if err != nil {
+		t.Fatalf("Grep() failed: %v", err)
+	}
+	if len(matches) != 5 {
+		t.Errorf("Expected matches to be capped at 5, got %d", len(matches))
+	}
+}
+
+func TestStoreRejectsUnknownIDs(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	for _, id := range []string{"missing", "../../etc/passwd", ""} {
+		if _, err := store.ReadLines(id, 1, 10); !errors.Is(err, ErrNotFound) {
+			t.Errorf("ReadLines(%q) error = %v, want ErrNotFound", id, err)
+		}
+	}
+}
+
+func TestHeadTail(t *testing.T) {
+	head, tail := HeadTail("short", 10)
+	if head != "short" || tail != "" {
+		t.Errorf("Expected short content to be returned whole, got %q / %q", head, tail)
+	}
+
+	content := strings.Repeat("line of log\n", 100)
+	head, tail = HeadTail(content, 50)
+	if len(head) > 50 || len(tail) > 50 {
+		t.Errorf("Expected at most 50 bytes each, got %d and %d", len(head), len(tail))
+	}
+	if strings.HasSuffix(head, "\n") || !strings.HasPrefix(tail, "line") {
+		t.Errorf("Expected cuts at line boundaries, got %q / %q", head, tail)
+	}
+
+	head, _ = HeadTail(strings.Repeat("构建日志", 30), 10)
+	if !strings.HasPrefix("构建日志", head) || head == "" {
+		t.Errorf("Expected the head not to split a character, got %q", head)
+	}
+}
@@ -0,0 +1,265 @@
+// Package audit keeps an append-only JSONL record of every tool call.
+// Each entry carries the hash of the previous one, so edited, removed or
+// reordered lines are detected by Verify.
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status values of an entry
+const (
+	StatusOK        = "ok"
+	StatusError     = "error"
+	StatusTimeout   = "timeout"
+	StatusDenied    = "denied"
+	StatusBlocked   = "blocked"
+	StatusCancelled = "cancelled"
+)
+
+// Entry is one tool call
+type Entry struct {
+	Time       time.Time              `json:"time"`
+	SessionID  string                 `json:"session_id,omitempty"`
+	User       string                 `json:"user"`
+	Tool       string                 `json:"tool"`
+	Server     string                 `json:"server,omitempty"`
+	Arguments  map[string]interface{} `json:"arguments,omitempty"`
+	Class      string                 `json:"class"`    // read, write or destructive
+	Approval   string                 `json:"approval"` // How the call was allowed or refused
+	Status     string                 `json:"status"`
+	Error      string                 `json:"error,omitempty"`
+	DurationMS int64                  `json:"duration_ms"`
+	PrevHash   string                 `json:"prev_hash"`
+	Hash       string                 `json:"hash"`
+}
+
+// Repo returns the repository the call targeted, from its "repo" argument
+func (e *Entry) Repo() string {
+	repo, _ := e.Arguments["repo"].(string)
+	return repo
+}
+
+// Log appends entries to a JSONL file. Several processes may append to the
+// same file: each append holds an exclusive lock on it and chains to the
+// entry actually last in the file.
+type Log struct {
+	path string
+	mu   sync.Mutex
+	last string // Hash of the last entry when the file was size bytes long
+	size int64
+}
+
+// Open returns the log stored at path; the file is created on first append
+func Open(path string) *Log {
+	return &Log{path: path, size: -1}
+}
+
+// DefaultPath returns ~/.cnb-assistant/audit.jsonl
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate home directory: %w", err)
+	}
+	return filepath.Join(home, ".cnb-assistant", "audit.jsonl"), nil
+}
+
+// Path returns the file holding the log
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Append chains e to the previous entry and writes it as one line.
+// PrevHash and Hash are filled in.
+func (l *Log) Append(e *Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	// Hold the lock from reading the last hash until the entry is written,
+	// so another process cannot append in between
+	if err := lockFile(f); err != nil {
+		return fmt.Errorf("failed to lock audit log: %w", err)
+	}
+	defer unlockFile(f)
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+	if info.Size() != l.size {
+		// Another process appended since, or this is the first append
+		if l.last, err = lastHash(l.path); err != nil {
+			return err
+		}
+	}
+
+	// UTC without monotonic reading, so the entry hashes the same after decoding
+	e.Time = e.Time.UTC().Round(0)
+	e.PrevHash = l.last
+	hash, err := entryHash(e)
+	if err != nil {
+		return err
+	}
+	e.Hash = hash
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	line = append(line, '\n')
+	if _, err := f.Write(line); err != nil {
+		l.size = -1
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	l.last, l.size = e.Hash, info.Size()+int64(len(line))
+	return nil
+}
+
+// Filter selects entries; zero fields match everything
+type Filter struct {
+	Since time.Time
+	Until time.Time
+	Tool  string // Exact tool name
+	Repo  string // Repository, or a prefix such as an organization ending in "/"
+}
+
+// Match reports whether e passes the filter
+func (f Filter) Match(e *Entry) bool {
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+	if f.Tool != "" && e.Tool != f.Tool {
+		return false
+	}
+	if f.Repo != "" {
+		repo := e.Repo()
+		if repo != f.Repo && !(strings.HasSuffix(f.Repo, "/") && strings.HasPrefix(repo, f.Repo)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Query returns the entries of the log at path that match f, oldest first.
+// A missing log has no entries.
+func Query(path string, f Filter) ([]*Entry, error) {
+	var entries []*Entry
+	err := scan(path, func(n int, e *Entry) error {
+		if e == nil {
+			return fmt.Errorf("audit log line %d is not a valid entry", n)
+		}
+		if f.Match(e) {
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// Verify checks the hash chain of the log at path and returns the number of entries.
+// The error names the first line that was modified, removed or reordered.
+func Verify(path string) (int, error) {
+	count, prev := 0, ""
+	err := scan(path, func(n int, e *Entry) error {
+		if e == nil {
+			return fmt.Errorf("line %d is not a valid entry", n)
+		}
+		if e.PrevHash != prev {
+			return fmt.Errorf("line %d does not follow line %d (an entry was removed, inserted or reordered)", n, n-1)
+		}
+		hash, err := entryHash(e)
+		if err != nil {
+			return err
+		}
+		if hash != e.Hash {
+			return fmt.Errorf("line %d was modified", n)
+		}
+		prev = e.Hash
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// entryHash hashes the entry without its own Hash, chained to PrevHash
+func entryHash(e *Entry) (string, error) {
+	c := *e
+	c.Hash = ""
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// lastHash returns the hash of the last entry in the log, "" when it is empty or missing
+func lastHash(path string) (string, error) {
+	last := ""
+	err := scan(path, func(n int, e *Entry) error {
+		if e == nil {
+			return fmt.Errorf("audit log line %d is not a valid entry", n)
+		}
+		last = e.Hash
+		return nil
+	})
+	return last, err
+}
+
+// scan calls fn for each non-empty line of the log with its 1-based number.
+// e is nil when the line cannot be decoded.
+func scan(path string, fn func(n int, e *Entry) error) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	for n := 1; ; n++ {
+		line, err := reader.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var e Entry
+			entry := &e
+			if json.Unmarshal(line, &e) != nil {
+				entry = nil
+			}
+			if ferr := fn(n, entry); ferr != nil {
+				return ferr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read audit log: %w", err)
+		}
+	}
+}
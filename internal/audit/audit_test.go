@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAppendQueryVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	entries := []*Entry{
+		{Time: start, Tool: "cnb_get_repository", Arguments: map[string]interface{}{"repo": "org/demo-app"}, Class: "read", Approval: "not_required", Status: StatusOK},
+		{Time: start.Add(time.Hour), Tool: "cnb_startBuild", Arguments: map[string]interface{}{"repo": "org/demo-app", "branch": "main"}, Class: "write", Approval: "approved", Status: StatusOK, DurationMS: 1200},
+		{Time: start.Add(2 * time.Hour), Tool: "cnb_merge_pull", Arguments: map[string]interface{}{"repo": "other/api"}, Class: "destructive", Approval: "denied", Status: StatusDenied},
+	}
+
+	// Two logs on the same file, as with two runs of the assistant
+	if err := Open(path).Append(entries[0]); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+	log := Open(path)
+	for _, e := range entries[1:] {
+		if err := log.Append(e); err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+	}
+	if entries[1].PrevHash != entries[0].Hash || entries[0].PrevHash != "" {
+		t.Fatal("Expected entries to be chained across log instances")
+	}
+
+	got, err := Query(path, Filter{Repo: "org/demo-app"})
+	if err != nil {
+		t.Fatalf("Query() failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("Expected 2 entries for org/demo-app, got %d", len(got))
+	}
+
+	got, _ = Query(path, Filter{Repo: "other/", Since: start.Add(30 * time.Minute)})
+	if len(got) != 1 || got[0].Tool != "cnb_merge_pull" {
+		t.Errorf("Expected the merge in other/, got %+v", got)
+	}
+
+	got, _ = Query(path, Filter{Tool: "cnb_startBuild", Until: start})
+	if len(got) != 0 {
+		t.Errorf("Expected no build before %s, got %d", start, len(got))
+	}
+
+	if n, err := Verify(path); err != nil || n != 3 {
+		t.Fatalf("Verify() = %d, %v; want 3 entries intact", n, err)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log := Open(path)
+	for _, tool := range []string{"cnb_get_repository", "cnb_buildLogsDelete", "cnb_list_pulls"} {
+		if err := log.Append(&Entry{Time: time.Now(), Tool: tool, Status: StatusOK}); err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+
+	tampered := map[string]string{
+		"modified": lines[0] + strings.Replace(lines[1], `"status":"ok"`, `"status":"denied"`, 1) + lines[2],
+		"removed":  lines[0] + lines[2],
+	}
+	for name, content := range tampered {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Verify(path); err == nil || !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("%s: expected Verify() to flag line 2, got %v", name, err)
+		}
+	}
+}
+
+func TestTwoWritersKeepTheChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	a, b := Open(path), Open(path)
+
+	// Alternating appends, as from two assistants in two terminals
+	for i, log := range []*Log{a, b, a, b, a} {
+		if err := log.Append(&Entry{Time: time.Now(), Tool: fmt.Sprintf("tool_%d", i), Status: StatusOK}); err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+	}
+	if n, err := Verify(path); err != nil || n != 5 {
+		t.Fatalf("Verify() = %d, %v; want 5 entries intact", n, err)
+	}
+
+	// Concurrent appends
+	var wg sync.WaitGroup
+	for _, log := range []*Log{a, b} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				if err := log.Append(&Entry{Time: time.Now(), Tool: "cnb_get_repository", Status: StatusOK}); err != nil {
+					t.Errorf("Append() failed: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if n, err := Verify(path); err != nil || n != 45 {
+		t.Fatalf("Verify() = %d, %v; want 45 entries intact", n, err)
+	}
+}
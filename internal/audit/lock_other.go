@@ -0,0 +1,16 @@
+//go:build !unix
+
+package audit
+
+import "os"
+
+// lockFile is a no-op where flock is unavailable; appends from one process
+// are still serialized by Log's mutex
+func lockFile(f *os.File) error {
+	return nil
+}
+
+// unlockFile releases the lock taken by lockFile
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"cnb.cool/znb/learn-skills/internal/policy"
+	"cnb.cool/znb/learn-skills/internal/redact"
+)
+
+// authorize classifies a tool call and, unless it only reads, asks the
+// approver before it may run. Calls outside the allowed-tools of the
+// loaded skills are refused first. Denied calls return an error wrapping policy.ErrDenied.
+// The checked request and how it was decided are returned for the audit log.
+func (a *Assistant) authorize(ctx context.Context, toolName, argumentsJSON string) (policy.Request, policy.Approval, error) {
+	req := policy.Request{Tool: toolName}
+	if server, _, ok := a.MCP.Lookup(toolName); ok {
+		req.Server = server
+	}
+	// Arguments are only shown and recorded; invalid JSON is reported by the tool itself
+	json.Unmarshal([]byte(argumentsJSON), &req.Arguments)
+
+	if a.Policy != nil {
+		req.Class = a.Policy.Classify(toolName, a.MCP.Annotations(toolName))
+	}
+	// Classified first so a refused call is audited with its real class
+	if err := a.checkSkillTools(toolName); err != nil {
+		return req, policy.Refused, err
+	}
+	if a.Policy == nil {
+		return req, policy.NotRequired, nil
+	}
+
+	// Concurrent calls ask one at a time
+	a.approvalMu.Lock()
+	defer a.approvalMu.Unlock()
+	approval, err := a.Policy.Authorize(ctx, req, a.Approve)
+	return req, approval, err
+}
+
+// terminalApprover asks on the terminal before write and destructive calls,
+// reading the answer from the interactive input
+func terminalApprover(input *lineReader, redactor *redact.Redactor) policy.Approver {
+	return func(ctx context.Context, req policy.Request) (policy.Decision, error) {
+		fmt.Print(redactor.String(formatApprovalPrompt(req)))
+		for {
+			fmt.Print("   是否执行？[y]es / [n]o / [a]lways: ")
+			answer, err := input.ReadLine(ctx)
+			if err != nil {
+				fmt.Println()
+				return policy.Deny, err
+			}
+
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "y", "yes":
+				return policy.Allow, nil
+			case "a", "always":
+				return policy.AllowAlways, nil
+			case "n", "no", "":
+				return policy.Deny, nil
+			}
+		}
+	}
+}
+
+// formatApprovalPrompt describes a tool call awaiting approval
+func formatApprovalPrompt(req policy.Request) string {
+	var sb strings.Builder
+
+	if req.Class == policy.Destructive {
+		sb.WriteString("\n🛑 即将执行不可恢复的操作：")
+	} else {
+		sb.WriteString("\n✋ 即将执行写操作：")
+	}
+	sb.WriteString(formatServerTool(req.Server, req.Tool))
+	sb.WriteString("\n   参数：")
+	sb.WriteString(formatArguments(req.Arguments))
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -1,163 +1,293 @@
 package cli
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
 
 	"cnb.cool/znb/learn-skills/internal/llm"
+	"cnb.cool/znb/learn-skills/internal/mcp"
+	"cnb.cool/znb/learn-skills/internal/policy"
+	"cnb.cool/znb/learn-skills/internal/redact"
+	"cnb.cool/znb/learn-skills/internal/sandbox"
 )
 
 // printPendingMCPCallEndings prints all pending MCP call ending info and clears the list
 func (a *Assistant) printPendingMCPCallEndings() {
 	for _, info := range a.pendingMCPCallEnding {
-		fmt.Print(formatMCPCallEnd(info))
+		fmt.Print(a.redact(formatMCPCallEnd(info)))
 	}
 	// Clear the list
 	a.pendingMCPCallEnding = nil
 }
 
-// Initialize sets up the assistant with system prompt
+// Initialize connects to the MCP servers, discovers their tools and sets up the assistant with system prompt
 func (a *Assistant) Initialize() error {
-	// Add skill as system message
+	ctx, cancel := withTimeout(context.Background(), a.Config.Timeouts.Connect)
+	defer cancel()
+	if err := a.MCP.Initialize(ctx); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("MCP servers did not connect within %s (timeouts.connect): %w", a.Config.Timeouts.Connect, err)
+		}
+		if errors.Is(err, mcp.ErrUnsupportedTransport) {
+			return fmt.Errorf("MCP endpoint speaks an unsupported transport (check cnb.mcp_url and cnb.transport): %w", err)
+		}
+		return fmt.Errorf("failed to connect to MCP server: %w", err)
+	}
+	for _, tool := range a.MCP.Tools() {
+		if _, err := mcpToolToLLM(tool); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  已跳过工具：%v\n", err)
+		}
+	}
+
+	// Add the skill catalog as system message
 	a.Messages = append(a.Messages, llm.Message{
 		Role:    "system",
-		Content: a.Skill,
+		Content: a.systemPrompt(),
 	})
 
 	return nil
 }
 
-// ProcessMessage handles a user message and returns the assistant's response
-func (a *Assistant) ProcessMessage(userMessage string) (string, error) {
+// ProcessMessage handles a user message and returns the assistant's response.
+// If ctx is cancelled the partial turn is rolled back from the history.
+func (a *Assistant) ProcessMessage(parent context.Context, userMessage string) (response string, err error) {
+	a.turnStart, a.turnSkills = len(a.Messages), len(a.loadedSkills)
+	defer a.rollbackOnError(&err)
+
+	ctx, cancel := withTimeout(parent, a.Config.Timeouts.Turn)
+	defer cancel()
+	defer a.explainTurnTimeout(parent, ctx, &err)
+
 	// Add user message
 	a.Messages = append(a.Messages, llm.Message{
 		Role:    "user",
-		Content: userMessage,
+		Content: a.redact(userMessage),
 	})
 
 	// Get CNB tools
-	tools := GetCNBTools()
-
-	// Tool calling loop (max 10 iterations to prevent infinite loops)
-	maxIterations := 10
-	for i := 0; i < maxIterations; i++ {
-		// Use streaming for final response (when no tool calls expected)
-		// For now, we'll always use non-streaming to handle tool calls properly
-		// In the future, we can detect if tools might be needed and choose accordingly
-
-		resp, err := a.LLMClient.Chat(a.Messages, tools)
-		if err != nil {
-			return "", fmt.Errorf("LLM call failed: %w", err)
-		}
-
-		if len(resp.Choices) == 0 {
-			return "", fmt.Errorf("no response from LLM")
-		}
-
-		assistantMsg := resp.Choices[0].Message
-		finishReason := resp.Choices[0].FinishReason
-
-		// Add assistant response to history
-		a.Messages = append(a.Messages, assistantMsg)
-
-		// Check if LLM wants to call tools
-		if finishReason == "tool_calls" && len(assistantMsg.ToolCalls) > 0 {
-			// Execute each tool call
-			for _, toolCall := range assistantMsg.ToolCalls {
-				result, err := a.ExecuteTool(toolCall.Function.Name, toolCall.Function.Arguments)
-
-				var toolResultContent string
-				if err != nil {
-					toolResultContent = fmt.Sprintf("Error: %v", err)
-				} else {
-					toolResultContent = result
-				}
-
-				// Add tool result to messages
-				a.Messages = append(a.Messages, llm.Message{
-					Role:       "tool",
-					Content:    toolResultContent,
-					ToolCallID: toolCall.ID,
-				})
-			}
-			// Continue loop to let LLM process tool results
-			continue
-		}
-
-		// LLM finished (no more tool calls)
-		// Print any pending MCP call ending info
-		a.printPendingMCPCallEndings()
-		return assistantMsg.Content, nil
-	}
+	tools := a.GetCNBTools()
 
-	return "", fmt.Errorf("exceeded maximum tool calling iterations")
+	return a.runToolLoop(ctx, tools, nil)
 }
 
 // ProcessMessageStream handles a user message with streaming output
-// callback is called for each chunk of the response
-func (a *Assistant) ProcessMessageStream(userMessage string, callback llm.StreamCallback) (string, error) {
+// callback is called for each chunk of the response, with secrets masked.
+// If ctx is cancelled the partial turn is rolled back from the history.
+func (a *Assistant) ProcessMessageStream(parent context.Context, userMessage string, callback llm.StreamCallback) (response string, err error) {
+	a.turnStart, a.turnSkills = len(a.Messages), len(a.loadedSkills)
+	defer a.rollbackOnError(&err)
+
+	ctx, cancel := withTimeout(parent, a.Config.Timeouts.Turn)
+	defer cancel()
+	defer a.explainTurnTimeout(parent, ctx, &err)
+
 	// Add user message
 	a.Messages = append(a.Messages, llm.Message{
 		Role:    "user",
-		Content: userMessage,
+		Content: a.redact(userMessage),
 	})
 
 	// Get CNB tools
-	tools := GetCNBTools()
-
-	// Tool calling loop (max 10 iterations to prevent infinite loops)
-	maxIterations := 10
-	for i := 0; i < maxIterations; i++ {
-		// Call LLM with streaming
-		resp, err := a.LLMClient.ChatStream(a.Messages, tools, callback)
-		if err != nil {
-			return "", fmt.Errorf("LLM call failed: %w", err)
+	tools := a.GetCNBTools()
+
+	return a.runToolLoop(ctx, tools, callback)
+}
+
+// chat sends the history to the LLM, streaming to callback when it is not nil.
+// Streamed text reaches callback redacted.
+// Older turns are compacted first if the history no longer fits the model.
+// The request is bounded by the configured LLM timeout.
+func (a *Assistant) chat(ctx context.Context, tools []llm.Tool, callback llm.StreamCallback) (*llm.ChatResponse, error) {
+	if err := a.compactHistory(ctx, tools); err != nil {
+		return nil, err
+	}
+
+	timeout := a.Config.Timeouts.LLM
+	llmCtx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	var resp *llm.ChatResponse
+	var err error
+	if callback != nil {
+		// Secrets split across chunks are only caught on the joined text
+		stream := redact.NewStream(a.Redactor, callback)
+		resp, err = a.LLMClient.ChatStream(llmCtx, a.Messages, tools, stream.Write)
+		if flushErr := stream.Flush(); err == nil {
+			err = flushErr
+		}
+	} else {
+		resp, err = a.LLMClient.Chat(llmCtx, a.Messages, tools)
+	}
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(llmCtx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("LLM call timed out after %s", timeout)
 		}
+		return nil, fmt.Errorf("LLM call failed: %w", err)
+	}
+	return resp, nil
+}
+
+// executeToolCalls runs the tool calls of one assistant message, up to
+// tools.concurrency at a time, and appends their results to the history in
+// the order the model made them. Each call has its own timeout; tool
+// failures, including timeouts, are reported to the model as structured
+// errors and only the end of ctx aborts the turn.
+func (a *Assistant) executeToolCalls(ctx context.Context, toolCalls []llm.ToolCall) error {
+	results := make([]string, len(toolCalls))
+	limit := max(a.Config.Tools.Concurrency, 1)
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
 
-		if len(resp.Choices) == 0 {
-			return "", fmt.Errorf("no response from LLM")
+	pending := len(a.pendingMCPCallEnding)
+start:
+	for i, toolCall := range toolCalls {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break start
 		}
 
-		assistantMsg := resp.Choices[0].Message
-		finishReason := resp.Choices[0].FinishReason
-
-		// Add assistant response to history
-		a.Messages = append(a.Messages, assistantMsg)
-
-		// Check if LLM wants to call tools
-		if finishReason == "tool_calls" && len(assistantMsg.ToolCalls) > 0 {
-			// Execute each tool call (non-streaming)
-			for _, toolCall := range assistantMsg.ToolCalls {
-				result, err := a.ExecuteTool(toolCall.Function.Name, toolCall.Function.Arguments)
-
-				var toolResultContent string
-				if err != nil {
-					toolResultContent = fmt.Sprintf("Error: %v", err)
-				} else {
-					toolResultContent = result
-				}
-
-				// Add tool result to messages
-				a.Messages = append(a.Messages, llm.Message{
-					Role:       "tool",
-					Content:    toolResultContent,
-					ToolCallID: toolCall.ID,
-				})
-			}
-			// Continue loop with tool results, use streaming for next response
-			continue
+		callCtx := ctx
+		if limit > 1 && len(toolCalls) > 1 {
+			callCtx = withCallLabel(ctx, fmt.Sprintf("[%d/%d]", i+1, len(toolCalls)))
 		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = a.runToolCall(callCtx, toolCall)
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// Concurrent calls finish in any order; report them in the order they started
+	batch := a.pendingMCPCallEnding[pending:]
+	sort.SliceStable(batch, func(i, j int) bool { return batch[i].StartTime.Before(batch[j].StartTime) })
 
-		// LLM finished (no more tool calls)
-		// Print any pending MCP call ending info
-		a.printPendingMCPCallEndings()
-		return assistantMsg.Content, nil
+	for i, toolCall := range toolCalls {
+		a.Messages = append(a.Messages, llm.Message{
+			Role:       "tool",
+			Content:    results[i],
+			ToolCallID: toolCall.ID,
+		})
 	}
+	return nil
+}
 
-	return "", fmt.Errorf("exceeded maximum tool calling iterations")
+// runToolCall executes one tool call under the tool timeout and returns the
+// result to show the model, redacted and within the tool output limit
+func (a *Assistant) runToolCall(ctx context.Context, toolCall llm.ToolCall) string {
+	timeout := a.Config.Timeouts.Tool
+	toolCtx, cancel := withTimeout(ctx, timeout)
+	result, err := a.ExecuteTool(toolCtx, toolCall.Function.Name, toolCall.Function.Arguments)
+	timedOut := errors.Is(toolCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil
+	cancel()
+
+	var toolResultContent string
+	switch {
+	case timedOut:
+		toolResultContent = toolErrorContent(toolCall.Function.Name, "timeout",
+			fmt.Sprintf("tool call did not finish within %s and was cancelled", timeout), result)
+	case errors.Is(err, policy.ErrDenied):
+		toolResultContent = toolErrorContent(toolCall.Function.Name, "denied", err.Error(), "")
+	case errors.Is(err, sandbox.ErrBlocked):
+		toolResultContent = toolErrorContent(toolCall.Function.Name, "blocked", err.Error(), "")
+	case err != nil:
+		toolResultContent = toolErrorContent(toolCall.Function.Name, "error", err.Error(), "")
+	default:
+		toolResultContent = result
+	}
+	toolResultContent = a.redact(toolResultContent)
+	// Skill instructions are only useful whole
+	if toolCall.Function.Name != readToolOutputTool.Function.Name && toolCall.Function.Name != loadSkillTool.Function.Name {
+		toolResultContent = a.limitToolOutput(toolCall.Function.Name, toolResultContent)
+	}
+	return toolResultContent
 }
 
-// Reset clears conversation history (keeps system message)
+// callLabelKey is the context key of a tool call's label
+type callLabelKey struct{}
+
+// withCallLabel attaches a label such as "[2/5]" that banners of a call
+// running alongside others are prefixed with
+func withCallLabel(ctx context.Context, label string) context.Context {
+	return context.WithValue(ctx, callLabelKey{}, label)
+}
+
+// callLabel returns the label attached by withCallLabel, "" if none
+func callLabel(ctx context.Context) string {
+	label, _ := ctx.Value(callLabelKey{}).(string)
+	return label
+}
+
+// toolError is the tool result reported to the model when a tool call fails
+type toolError struct {
+	Error struct {
+		Type    string `json:"type"` // "timeout", "denied", "blocked", "repeated" or "error"
+		Tool    string `json:"tool"`
+		Message string `json:"message"`
+		Output  string `json:"partial_output,omitempty"`
+	} `json:"error"`
+}
+
+// toolErrorContent renders a tool failure as JSON so the model can tell
+// timeouts and refusals apart from other errors and decide how to recover
+func toolErrorContent(tool, kind, message, output string) string {
+	var e toolError
+	e.Error.Type = kind
+	e.Error.Tool = tool
+	e.Error.Message = message
+	e.Error.Output = output
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Sprintf("Error: %s", message)
+	}
+	return string(data)
+}
+
+// withTimeout bounds ctx by d; a zero d means no limit
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// explainTurnTimeout replaces the error of a turn that ran out of time
+// with one naming the configured limit
+func (a *Assistant) explainTurnTimeout(parent, ctx context.Context, err *error) {
+	if *err != nil && parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		*err = fmt.Errorf("turn timed out after %s (timeouts.turn)", a.Config.Timeouts.Turn)
+	}
+}
+
+// rollbackOnError drops everything appended to the history since the turn
+// started when it failed, so an aborted turn leaves no dangling user message or tool calls
+func (a *Assistant) rollbackOnError(err *error) {
+	if *err == nil {
+		return
+	}
+	a.Messages = a.Messages[:a.turnStart]
+	a.loadedSkills = a.loadedSkills[:a.turnSkills]
+	a.pendingMCPCallEnding = nil
+}
+
+// Reset clears conversation history (keeps system message) and starts a new session
 func (a *Assistant) Reset() {
 	systemMsg := a.Messages[0]
 	a.Messages = []llm.Message{systemMsg}
+	a.loadedSkills = nil
+	if a.Sessions != nil {
+		a.Session = a.Sessions.New()
+	}
 }
@@ -0,0 +1,369 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cnb.cool/znb/learn-skills/internal/config"
+	"cnb.cool/znb/learn-skills/internal/llm"
+	"cnb.cool/znb/learn-skills/internal/mcp"
+	"cnb.cool/znb/learn-skills/internal/skill"
+)
+
+// chatRequest is the part of a chat completion request the fake LLM looks at
+type chatRequest struct {
+	Messages []llm.Message `json:"messages"`
+	Tools    []llm.Tool    `json:"tools"`
+	Stream   bool          `json:"stream"`
+}
+
+// fakeLLM is an OpenAI-compatible server answering with reply
+type fakeLLM struct {
+	mu       sync.Mutex
+	requests []chatRequest
+	reply    func(req chatRequest) llm.Message
+}
+
+// newFakeLLM starts a fake LLM and returns a client for it
+func newFakeLLM(t *testing.T, reply func(req chatRequest) llm.Message) (*fakeLLM, *llm.Client) {
+	t.Helper()
+
+	f := &fakeLLM{reply: reply}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.requests = append(f.requests, req)
+		f.mu.Unlock()
+
+		msg := f.reply(req)
+		msg.Role = "assistant"
+		finish := "stop"
+		if len(msg.ToolCalls) > 0 {
+			finish = "tool_calls"
+		}
+		if req.Stream {
+			writeStream(w, msg, finish)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      "chatcmpl-1",
+			"object":  "chat.completion",
+			"model":   "test-model",
+			"choices": []map[string]interface{}{{"index": 0, "message": msg, "finish_reason": finish}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := llm.NewClient("test-key", server.URL, "test-model")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, client
+}
+
+// writeStream sends msg as a chat completion event stream, one word of content per chunk
+func writeStream(w http.ResponseWriter, msg llm.Message, finish string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	send := func(delta map[string]interface{}, finish interface{}) {
+		chunk, _ := json.Marshal(map[string]interface{}{
+			"id":      "chatcmpl-1",
+			"object":  "chat.completion.chunk",
+			"model":   "test-model",
+			"choices": []map[string]interface{}{{"index": 0, "delta": delta, "finish_reason": finish}},
+		})
+		fmt.Fprintf(w, "data: %s\n\n", chunk)
+	}
+
+	send(map[string]interface{}{"role": "assistant"}, nil)
+	for _, word := range strings.SplitAfter(msg.Content, " ") {
+		if word != "" {
+			send(map[string]interface{}{"content": word}, nil)
+		}
+	}
+	for i, call := range msg.ToolCalls {
+		send(map[string]interface{}{"tool_calls": []map[string]interface{}{{
+			"index": i, "id": call.ID, "type": "function",
+			"function": map[string]interface{}{"name": call.Function.Name, "arguments": call.Function.Arguments},
+		}}}, nil)
+	}
+	send(map[string]interface{}{}, finish)
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+// Requests returns the requests received so far
+func (f *fakeLLM) Requests() []chatRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]chatRequest(nil), f.requests...)
+}
+
+// toolCall builds a tool call as the model would make it
+func toolCall(id, name, args string) llm.ToolCall {
+	var call llm.ToolCall
+	call.ID, call.Type = id, "function"
+	call.Function.Name, call.Function.Arguments = name, args
+	return call
+}
+
+// newTestAssistant returns an assistant with default settings, no MCP
+// servers and the skills given as directory name to SKILL.md content
+func newTestAssistant(t *testing.T, client *llm.Client, skills map[string]string) *Assistant {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range skills {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name, skill.FileName), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	registry, err := skill.Load([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Context:    config.ContextConfig{KeepTurns: 2},
+		ToolOutput: config.ToolOutputConfig{MaxBytes: 16384},
+		Tools:      config.ToolsConfig{Concurrency: 4, MaxIterations: 10, MaxRepeats: 3},
+	}
+	a := NewAssistant(cfg, client, mcp.NewManager(), registry, nil, nil, nil, nil, nil)
+	a.Messages = []llm.Message{{Role: "system", Content: a.systemPrompt()}}
+	return a
+}
+
+// fakeTools is an MCP transport offering one tool, "work", whose calls run handle
+type fakeTools struct {
+	mu         sync.Mutex
+	running    int
+	maxRunning int
+	handle     func(ctx context.Context, n int) error
+}
+
+func (f *fakeTools) Connect(ctx context.Context) error                     { return nil }
+func (f *fakeTools) Notify(ctx context.Context, n *mcp.Notification) error { return nil }
+func (f *fakeTools) Close() error                                          { return nil }
+
+func (f *fakeTools) Send(ctx context.Context, req *mcp.Request, notify mcp.NotifyFunc) (*mcp.Response, error) {
+	var result interface{}
+	switch req.Method {
+	case "initialize":
+		result = map[string]interface{}{
+			"protocolVersion": mcp.ProtocolVersion,
+			"capabilities":    map[string]interface{}{},
+			"serverInfo":      map[string]interface{}{"name": "fake", "version": "1.0"},
+		}
+	case "tools/list":
+		result = map[string]interface{}{"tools": []map[string]interface{}{{"name": "work"}}}
+	case "tools/call":
+		args := req.Params.(map[string]interface{})["arguments"].(map[string]interface{})
+		n := int(args["n"].(float64))
+
+		f.mu.Lock()
+		f.running++
+		f.maxRunning = max(f.maxRunning, f.running)
+		f.mu.Unlock()
+		err := f.handle(ctx, n)
+		f.mu.Lock()
+		f.running--
+		f.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		result = map[string]interface{}{"content": []map[string]interface{}{{"type": "text", "text": fmt.Sprintf("result %d", n)}}}
+	}
+
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.Response{JSONRPC: "2.0", ID: json.RawMessage(fmt.Sprint(req.ID)), Result: raw}, nil
+}
+
+// MaxRunning returns the most calls that ran at once
+func (f *fakeTools) MaxRunning() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.maxRunning
+}
+
+// withFakeTools connects a to tools as its only MCP server
+func withFakeTools(t *testing.T, a *Assistant, tools *fakeTools) {
+	t.Helper()
+
+	a.MCP.Add("fake", "", mcp.NewClientWithTransport("fake", tools))
+	if err := a.MCP.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// workCalls returns n calls of the fake "work" tool
+func workCalls(n int) []llm.ToolCall {
+	calls := make([]llm.ToolCall, n)
+	for i := range calls {
+		calls[i] = toolCall(fmt.Sprintf("call-%d", i), "work", fmt.Sprintf(`{"n":%d}`, i))
+	}
+	return calls
+}
+
+func TestExecuteToolCallsKeepsOrder(t *testing.T) {
+	// Every call waits for the one after it, so they finish in reverse order
+	const calls = 4
+	done := make([]chan struct{}, calls+1)
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	close(done[calls])
+
+	tools := &fakeTools{handle: func(ctx context.Context, n int) error {
+		defer close(done[n])
+		select {
+		case <-done[n+1]:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}}
+	a := newTestAssistant(t, nil, nil)
+	a.Config.Tools.Concurrency = calls
+	withFakeTools(t, a, tools)
+
+	start := len(a.Messages)
+	if err := a.executeToolCalls(context.Background(), workCalls(calls)); err != nil {
+		t.Fatal(err)
+	}
+
+	results := a.Messages[start:]
+	if len(results) != calls {
+		t.Fatalf("Expected %d tool messages, got %d", calls, len(results))
+	}
+	for i, msg := range results {
+		if msg.Role != "tool" || msg.ToolCallID != fmt.Sprintf("call-%d", i) || !strings.Contains(msg.Content, fmt.Sprintf("result %d", i)) {
+			t.Errorf("Message %d out of order: %+v", i, msg)
+		}
+	}
+}
+
+func TestExecuteToolCallsLimit(t *testing.T) {
+	tools := &fakeTools{handle: func(ctx context.Context, n int) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}}
+	a := newTestAssistant(t, nil, nil)
+	a.Config.Tools.Concurrency = 2
+	withFakeTools(t, a, tools)
+
+	if err := a.executeToolCalls(context.Background(), workCalls(6)); err != nil {
+		t.Fatal(err)
+	}
+	if got := tools.MaxRunning(); got != 2 {
+		t.Errorf("Expected at most 2 calls at once, got %d", got)
+	}
+}
+
+func TestExecuteToolCallsCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The second call cancels the turn while the first still runs
+	tools := &fakeTools{handle: func(callCtx context.Context, n int) error {
+		if n == 1 {
+			cancel()
+			return nil
+		}
+		<-callCtx.Done()
+		return callCtx.Err()
+	}}
+	a := newTestAssistant(t, nil, nil)
+	withFakeTools(t, a, tools)
+
+	start := len(a.Messages)
+	if err := a.executeToolCalls(ctx, workCalls(3)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if len(a.Messages) != start {
+		t.Errorf("Expected no tool messages after cancellation, got %+v", a.Messages[start:])
+	}
+}
+
+func TestProcessMessageStreamCancelRollsBack(t *testing.T) {
+	cases := []struct {
+		name string
+		// reply is the model's answer once the skill is loaded
+		reply llm.Message
+		// cancelOnChunk cancels the turn from the stream callback
+		cancelOnChunk bool
+	}{
+		{name: "during a tool call", reply: llm.Message{ToolCalls: workCalls(1)}},
+		{name: "while streaming the reply", reply: llm.Message{Content: "Build one passed and build two failed."}, cancelOnChunk: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			fake, client := newFakeLLM(t, func(req chatRequest) llm.Message {
+				if last := req.Messages[len(req.Messages)-1]; last.Role == "user" {
+					return llm.Message{ToolCalls: []llm.ToolCall{toolCall("c1", "load_skill", `{"name":"notes"}`)}}
+				}
+				return tc.reply
+			})
+			a := newTestAssistant(t, client, testSkills)
+			withFakeTools(t, a, &fakeTools{handle: func(callCtx context.Context, n int) error {
+				cancel()
+				<-callCtx.Done()
+				return callCtx.Err()
+			}})
+			a.loadedSkills = []string{"cnb-skill"}
+			before := append([]llm.Message(nil), a.Messages...)
+
+			var chunks []string
+			_, err := a.ProcessMessageStream(ctx, "Summarise my builds", func(chunk string) error {
+				chunks = append(chunks, chunk)
+				if tc.cancelOnChunk {
+					cancel()
+				}
+				return nil
+			})
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("Expected context.Canceled, got %v", err)
+			}
+			if got := len(fake.Requests()); got != 2 {
+				t.Fatalf("Expected the turn cancelled on the second model call, got %d calls", got)
+			}
+			if tc.cancelOnChunk && len(chunks) == 0 {
+				t.Fatal("Expected part of the reply streamed before the cancellation")
+			}
+
+			if len(a.Messages) != len(before) {
+				t.Fatalf("Expected %d messages after the rollback, got %+v", len(before), a.Messages)
+			}
+			for i := range before {
+				if a.Messages[i].Role != before[i].Role || a.Messages[i].Content != before[i].Content {
+					t.Errorf("Message %d changed: %+v", i, a.Messages[i])
+				}
+			}
+			if len(a.loadedSkills) != 1 || a.loadedSkills[0] != "cnb-skill" {
+				t.Errorf("Expected the skills loaded in the turn dropped, got %v", a.loadedSkills)
+			}
+		})
+	}
+}
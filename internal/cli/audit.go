@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"cnb.cool/znb/learn-skills/internal/audit"
+	"cnb.cool/znb/learn-skills/internal/policy"
+	"cnb.cool/znb/learn-skills/internal/sandbox"
+)
+
+// recordAudit appends a tool call to the audit log. A failed write is
+// reported but does not fail the call.
+func (a *Assistant) recordAudit(ctx context.Context, req policy.Request, approval policy.Approval, info MCPToolInfo, err error) {
+	if a.Audit == nil {
+		return
+	}
+
+	entry := &audit.Entry{
+		Time:       info.StartTime,
+		User:       currentUser(),
+		Tool:       info.ToolName,
+		Server:     info.Server,
+		Arguments:  a.redactArguments(info.Arguments),
+		Class:      req.Class.String(),
+		Approval:   string(approval),
+		Status:     auditStatus(ctx, err),
+		DurationMS: info.Duration().Milliseconds(),
+	}
+	if err != nil {
+		entry.Error = a.redact(err.Error())
+	}
+	if a.Session != nil {
+		entry.SessionID = a.Session.ID
+	}
+
+	if err := a.Audit.Append(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  写入审计日志失败：%v\n", err)
+	}
+}
+
+// auditStatus maps the outcome of a tool call to an audit status
+func auditStatus(ctx context.Context, err error) string {
+	switch {
+	case err == nil:
+		return audit.StatusOK
+	case errors.Is(err, policy.ErrDenied):
+		return audit.StatusDenied
+	case errors.Is(err, sandbox.ErrBlocked):
+		return audit.StatusBlocked
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return audit.StatusTimeout
+	case errors.Is(err, context.Canceled) || ctx.Err() != nil:
+		return audit.StatusCancelled
+	}
+	return audit.StatusError
+}
+
+// currentUser names the local user the assistant acts for
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// RunAudit implements the audit subcommand: it lists the entries of the audit
+// log matching the filters in args, or verifies the log's hash chain.
+// path is the configured log, "" for the default location.
+func RunAudit(name string, args []string, path string) error {
+	if path == "" {
+		defaultPath, err := audit.DefaultPath()
+		if err != nil {
+			return err
+		}
+		path = defaultPath
+	}
+
+	flags := flag.NewFlagSet(name+" audit", flag.ContinueOnError)
+	file := flags.String("file", path, "audit log to read; audit.path in config sets the default")
+	since := flags.String("since", "", "only entries at or after this time: RFC 3339, 2006-01-02 or a duration ago such as 24h")
+	until := flags.String("until", "", "only entries at or before this time, same formats as --since; a date includes that whole day")
+	tool := flags.String("tool", "", "only calls of this tool")
+	repo := flags.String("repo", "", "only calls on this repository; end with / to match a whole organization")
+	asJSON := flags.Bool("json", false, "print matching entries as JSON lines")
+	verify := flags.Bool("verify", false, "check that no entry was modified, removed or reordered")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	if *verify {
+		count, err := audit.Verify(*file)
+		if err != nil {
+			return fmt.Errorf("audit log %s failed verification: %w", *file, err)
+		}
+		fmt.Printf("Audit log %s is intact (%d entries).\n", *file, count)
+		return nil
+	}
+
+	var err error
+	filter := audit.Filter{Tool: *tool, Repo: *repo}
+	if filter.Since, err = parseAuditTime(*since, false); err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	if filter.Until, err = parseAuditTime(*until, true); err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+
+	entries, err := audit.Query(*file, filter)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No matching audit entries.")
+		return nil
+	}
+	for _, e := range entries {
+		args, _ := json.Marshal(e.Arguments)
+		fmt.Printf("%s  %-9s %-12s %6dms  %s  %s  %s\n",
+			e.Time.Local().Format("2006-01-02 15:04:05"), e.Status, e.Approval, e.DurationMS,
+			e.User, formatServerTool(e.Server, e.Tool), args)
+	}
+	return nil
+}
+
+// parseAuditTime accepts RFC 3339, a date, or a duration before now; "" is the zero time.
+// A date is the start of that day, or its last instant when endOfDay is set,
+// so --until 2025-01-02 includes the whole of January 2.
+func parseAuditTime(s string, endOfDay bool) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not an RFC 3339 time, a date or a duration", s)
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cnb.cool/znb/learn-skills/internal/audit"
+)
+
+func TestParseAuditTime(t *testing.T) {
+	day := time.Date(2025, 1, 2, 0, 0, 0, 0, time.Local)
+	cases := []struct {
+		input    string
+		endOfDay bool
+		want     time.Time
+	}{
+		{"", false, time.Time{}},
+		{"", true, time.Time{}},
+		{"2025-01-02", false, day},
+		{"2025-01-02", true, day.AddDate(0, 0, 1).Add(-time.Nanosecond)},
+		{"2025-01-02T15:04:05Z", false, time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"2025-01-02T15:04:05Z", true, time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)},
+	}
+
+	for _, tc := range cases {
+		got, err := parseAuditTime(tc.input, tc.endOfDay)
+		if err != nil {
+			t.Fatalf("parseAuditTime(%q, %v) failed: %v", tc.input, tc.endOfDay, err)
+		}
+		if !got.Equal(tc.want) {
+			t.Errorf("parseAuditTime(%q, %v) = %s, want %s", tc.input, tc.endOfDay, got, tc.want)
+		}
+	}
+
+	before := time.Now()
+	got, err := parseAuditTime("24h", true)
+	if err != nil || got.Before(before.Add(-24*time.Hour)) || got.After(time.Now().Add(-24*time.Hour)) {
+		t.Errorf("Expected 24h ago, got %s, %v", got, err)
+	}
+	if _, err := parseAuditTime("yesterday", false); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestRunAuditUntilDateIncludesThatDay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log := audit.Open(path)
+	for _, e := range []*audit.Entry{
+		{Time: time.Date(2025, 1, 2, 9, 0, 0, 0, time.Local), Tool: "morning_build", Status: audit.StatusOK},
+		{Time: time.Date(2025, 1, 2, 23, 30, 0, 0, time.Local), Tool: "late_build", Status: audit.StatusOK},
+		{Time: time.Date(2025, 1, 3, 0, 0, 0, 0, time.Local), Tool: "next_day_build", Status: audit.StatusOK},
+	} {
+		if err := log.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var err error
+	out := captureStdout(t, func() {
+		err = RunAudit("learn-skills", []string{"-file", path, "-since", "2025-01-02", "-until", "2025-01-02", "-json"}, path)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "morning_build") || !strings.Contains(out, "late_build") {
+		t.Errorf("Expected every entry of January 2, got:\n%s", out)
+	}
+	if strings.Contains(out, "next_day_build") {
+		t.Errorf("Expected no entry of January 3, got:\n%s", out)
+	}
+}
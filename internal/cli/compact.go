@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"cnb.cool/znb/learn-skills/internal/llm"
+)
+
+// summaryPrefix marks the message that holds the summary of compacted turns
+const summaryPrefix = "[Summary of earlier conversation]\n"
+
+// maxSummaryInput caps each message's content in the transcript sent for
+// summarization so a huge build log cannot overflow the summary request itself
+const maxSummaryInput = 2000
+
+// summaryInstruction asks the model to condense older turns
+const summaryInstruction = `Summarize the conversation below between a user and a CNB assistant so it can replace the original messages.
+Keep every fact still needed to continue: repositories, branches, build numbers, IDs, file paths, errors found and decisions made.
+Drop greetings, repeated output and raw logs. Answer with the summary only, in the language the user used.`
+
+// compactHistory summarizes older turns when the history and tools no longer
+// fit the model's prompt budget. The system message, the instructions of
+// loaded skills and the most recent context.keep_turns user turns are kept
+// verbatim.
+func (a *Assistant) compactHistory(ctx context.Context, tools []llm.Tool) error {
+	budget := a.Config.Context.BudgetFor(a.Config.LLM.Model)
+	used := llm.EstimateTokens(a.Messages) + llm.EstimateToolTokens(tools)
+	if used <= budget {
+		return nil
+	}
+
+	cut := a.compactionCut()
+	if cut <= 1 {
+		// Only recent turns are left; nothing can be summarized
+		return nil
+	}
+
+	summary, err := a.summarize(ctx, a.Messages[1:cut])
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		fmt.Fprintf(os.Stderr, "⚠️  压缩对话历史失败，已直接丢弃较早的消息：%s\n", a.redact(err.Error()))
+		summary = "Earlier messages were dropped to fit the context window and could not be summarized."
+	}
+
+	compacted := []llm.Message{
+		a.Messages[0],
+		{Role: "system", Content: summaryPrefix + a.redact(summary)},
+	}
+	// Loaded skills keep their instructions in full rather than as part of the summary
+	compacted = append(compacted, a.keptSkillMessages(a.Messages[cut:])...)
+	compacted = append(compacted, a.Messages[cut:]...)
+
+	fmt.Fprintf(os.Stderr, "🗜️  对话历史约 %d tokens，超过预算 %d，已将较早的 %d 条消息压缩为摘要\n",
+		used, budget, cut-1)
+
+	a.turnStart -= len(a.Messages) - len(compacted)
+	a.Messages = compacted
+	return nil
+}
+
+// compactionCut returns the index of the first message to keep verbatim:
+// the start of the oldest of the last context.keep_turns user turns.
+// Cutting at a user message never separates tool calls from their results.
+func (a *Assistant) compactionCut() int {
+	keep := a.Config.Context.KeepTurns
+	for i := len(a.Messages) - 1; i > 0; i-- {
+		if a.Messages[i].Role != "user" {
+			continue
+		}
+		keep--
+		if keep <= 0 {
+			return i
+		}
+	}
+	return 0
+}
+
+// summarize asks the LLM for a summary of messages, which may start with an
+// earlier summary that is folded into the new one
+func (a *Assistant) summarize(ctx context.Context, messages []llm.Message) (string, error) {
+	var transcript strings.Builder
+	for _, msg := range messages {
+		switch {
+		case msg.Role == "system" && strings.HasPrefix(msg.Content, skillPrefix):
+			// Carried over verbatim by keptSkillMessages
+			continue
+		case msg.Role == "system":
+			transcript.WriteString(strings.TrimPrefix(msg.Content, summaryPrefix))
+			transcript.WriteString("\n\n")
+			continue
+		case msg.Role == "tool":
+			transcript.WriteString("tool result: ")
+		default:
+			transcript.WriteString(msg.Role + ": ")
+		}
+		transcript.WriteString(clipText(msg.Content, maxSummaryInput))
+		for _, tc := range msg.ToolCalls {
+			fmt.Fprintf(&transcript, "\n  -> called %s(%s)", tc.Function.Name, clipText(tc.Function.Arguments, maxSummaryInput))
+		}
+		transcript.WriteString("\n\n")
+	}
+
+	request := []llm.Message{
+		{Role: "system", Content: summaryInstruction},
+		{Role: "user", Content: transcript.String()},
+	}
+
+	llmCtx, cancel := withTimeout(ctx, a.Config.Timeouts.LLM)
+	defer cancel()
+
+	resp, err := a.LLMClient.Chat(llmCtx, request, nil)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 || strings.TrimSpace(resp.Choices[0].Message.Content) == "" {
+		return "", fmt.Errorf("empty summary from LLM")
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
+
+// clipText shortens s to at most limit runes, noting how much was left out
+func clipText(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return fmt.Sprintf("%s…(%d characters omitted)", string(runes[:limit]), len(runes)-limit)
+}
@@ -1,155 +1,87 @@
 package cli
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"os/exec"
-	"regexp"
 	"strings"
 	"time"
+
+	"cnb.cool/znb/learn-skills/internal/mcp"
 )
 
-// parseMCPCommand parses an MCP tool call command
-// Returns: (tool name, arguments map, is MCP call)
-func parseMCPCommand(command string) (string, map[string]interface{}, bool) {
-	// Check if this is an MCP call command
-	if !strings.Contains(command, "cnb-mcp.py call") {
-		return "", nil, false
-	}
-
-	// Remove newlines and extra spaces
-	command = strings.ReplaceAll(command, "\n", " ")
-	command = strings.ReplaceAll(command, "\\", "")
-	command = regexp.MustCompile(`\s+`).ReplaceAllString(command, " ")
-	command = strings.TrimSpace(command)
-
-	// Find "call" keyword and extract everything after it
-	callIdx := strings.Index(command, "call ")
-	if callIdx == -1 {
-		return "", nil, false
-	}
-
-	// Get the part after "call "
-	afterCall := strings.TrimSpace(command[callIdx+5:])
-	if afterCall == "" {
-		return "", nil, false
-	}
-
-	// Split into tokens, respecting quotes
-	tokens := parseTokens(afterCall)
-	if len(tokens) == 0 {
-		return "", nil, false
-	}
-
-	// First token is the tool name
-	toolName := tokens[0]
-	args := make(map[string]interface{})
-
-	// Parse remaining tokens as key=value pairs
-	for _, token := range tokens[1:] {
-		if strings.Contains(token, "=") {
-			parts := strings.SplitN(token, "=", 2)
-			if len(parts) == 2 {
-				key := parts[0]
-				value := parts[1]
-
-				// Try to parse value as JSON
-				var jsonValue interface{}
-				if err := json.Unmarshal([]byte(value), &jsonValue); err == nil {
-					args[key] = jsonValue
-				} else {
-					// If JSON parsing fails, use as string
-					args[key] = value
-				}
-			}
-		}
-	}
+// formatMCPCallStart formats the output at the start of a tool call.
+// label tells concurrent calls apart and may be empty.
+func formatMCPCallStart(label, server, toolName string, args map[string]interface{}) string {
+	var sb strings.Builder
 
-	return toolName, args, true
+	sb.WriteString("\n📡 ")
+	if label != "" {
+		sb.WriteString(label + " ")
+	}
+	sb.WriteString("正在调用 MCP 工具：")
+	sb.WriteString(formatServerTool(server, toolName))
+	sb.WriteString("\n   参数：")
+	sb.WriteString(formatArguments(args))
+	sb.WriteString("\n")
+	return sb.String()
 }
 
-// parseTokens splits a string into tokens, respecting quoted strings
-func parseTokens(s string) []string {
-	var tokens []string
-	var current strings.Builder
-	inQuotes := false
-	quoteChar := rune(0)
-
-	for i, r := range s {
-		switch r {
-		case '"', '\'':
-			if !inQuotes {
-				inQuotes = true
-				quoteChar = r
-			} else if r == quoteChar {
-				inQuotes = false
-				quoteChar = 0
-			}
-			current.WriteRune(r)
-		case ' ':
-			if inQuotes {
-				current.WriteRune(r)
-			} else if current.Len() > 0 {
-				tokens = append(tokens, current.String())
-				current.Reset()
-			}
-		default:
-			current.WriteRune(r)
-		}
-
-		// Add last token if we're at the end
-		if i == len(s)-1 && current.Len() > 0 {
-			tokens = append(tokens, current.String())
-		}
+// formatMCPNotice formats a progress or log notification received during a tool call.
+// label tells concurrent calls apart and may be empty.
+func formatMCPNotice(label string, n mcp.Notice) string {
+	if label != "" {
+		label += " "
+	}
+	if n.Method == "notifications/message" {
+		return fmt.Sprintf("   📝 %s[%s] %s\n", label, n.Level, n.Message)
 	}
 
-	return tokens
-}
-
-// formatMCPCallStart formats the output at the start of a tool call
-func formatMCPCallStart(toolName string, args map[string]interface{}) string {
 	var sb strings.Builder
-
-	sb.WriteString("\n📡 正在调用 MCP 工具：")
-	sb.WriteString(toolName)
-	sb.WriteString("\n   参数：")
-
-	// Format arguments as JSON
-	if len(args) == 0 {
-		sb.WriteString("{}")
+	sb.WriteString("   ⏳ " + label + "进度：")
+	if n.Total > 0 {
+		sb.WriteString(fmt.Sprintf("%.0f%%", n.Progress/n.Total*100))
 	} else {
-		argsJSON, err := json.MarshalIndent(args, "   ", "  ")
-		if err != nil {
-			sb.WriteString(fmt.Sprintf("(无法格式化: %v)", args))
-		} else {
-			sb.WriteString(string(argsJSON))
-		}
+		sb.WriteString(fmt.Sprintf("%g", n.Progress))
+	}
+	if n.Message != "" {
+		sb.WriteString(" ")
+		sb.WriteString(n.Message)
 	}
-
 	sb.WriteString("\n")
 	return sb.String()
 }
 
+// formatServerTool renders a tool name together with the server that provides it
+func formatServerTool(server, toolName string) string {
+	if server == "" {
+		return toolName
+	}
+	return fmt.Sprintf("%s（服务器：%s）", toolName, server)
+}
+
+// formatArguments renders tool arguments as indented JSON for the banners
+// and the approval prompt
+func formatArguments(args map[string]interface{}) string {
+	if len(args) == 0 {
+		return "{}"
+	}
+	argsJSON, err := json.MarshalIndent(args, "   ", "  ")
+	if err != nil {
+		return fmt.Sprintf("(无法格式化: %v)", args)
+	}
+	return string(argsJSON)
+}
+
 // formatMCPCallEnd formats the output at the end of a tool call
 func formatMCPCallEnd(info MCPToolInfo) string {
 	var sb strings.Builder
 
 	sb.WriteString("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
 	sb.WriteString("ℹ️  数据来源：")
-	sb.WriteString(info.ToolName)
+	sb.WriteString(formatServerTool(info.Server, info.ToolName))
 	sb.WriteString("\n   参数：")
-
-	// Format arguments as JSON
-	if len(info.Arguments) == 0 {
-		sb.WriteString("{}")
-	} else {
-		argsJSON, err := json.MarshalIndent(info.Arguments, "   ", "  ")
-		if err != nil {
-			sb.WriteString(fmt.Sprintf("(无法格式化: %v)", info.Arguments))
-		} else {
-			sb.WriteString(string(argsJSON))
-		}
-	}
+	sb.WriteString(formatArguments(info.Arguments))
 
 	// Add duration
 	duration := info.Duration()
@@ -159,10 +91,33 @@ func formatMCPCallEnd(info MCPToolInfo) string {
 	return sb.String()
 }
 
-// ExecuteTool executes a tool call and returns the result
-func (a *Assistant) ExecuteTool(toolName string, argumentsJSON string) (string, error) {
+// ExecuteTool executes a tool call and returns the result.
+// Write and destructive calls only run once the policy approves them, and
+// every call, approved or not, is recorded in the audit log.
+// Cancelling ctx aborts the in-flight MCP request or kills the bash command.
+func (a *Assistant) ExecuteTool(ctx context.Context, toolName string, argumentsJSON string) (string, error) {
+	startTime := time.Now()
+	req, approval, err := a.authorize(ctx, toolName, argumentsJSON)
+
+	var result string
+	if err == nil {
+		result, err = a.executeTool(ctx, toolName, argumentsJSON)
+	}
+
+	a.recordAudit(ctx, req, approval, MCPToolInfo{
+		Server:    req.Server,
+		ToolName:  toolName,
+		Arguments: req.Arguments,
+		StartTime: startTime,
+		EndTime:   time.Now(),
+	}, err)
+	return result, err
+}
+
+// executeTool dispatches a tool call to the built-in tools or the MCP server providing it
+func (a *Assistant) executeTool(ctx context.Context, toolName string, argumentsJSON string) (string, error) {
 	switch toolName {
-	case "execute_bash":
+	case bashTool.Function.Name:
 		var args struct {
 			Command string `json:"command"`
 		}
@@ -170,45 +125,139 @@ func (a *Assistant) ExecuteTool(toolName string, argumentsJSON string) (string,
 			return "", fmt.Errorf("failed to parse arguments: %w", err)
 		}
 
-		// Parse MCP command
-		mcpToolName, mcpArgs, isMCP := parseMCPCommand(args.Command)
-
-		if isMCP {
-			// Output call start information
-			fmt.Print(formatMCPCallStart(mcpToolName, mcpArgs))
+		return a.Sandbox.Run(ctx, args.Command, a.cnbEnv())
+	case listResourcesTool.Function.Name:
+		var args struct {
+			Server string `json:"server"`
+		}
+		if err := unmarshalArguments(argumentsJSON, &args); err != nil {
+			return "", err
+		}
 
-			// Record start time and execute
-			startTime := time.Now()
-			result, err := executeBashCommand(args.Command)
+		return a.listMCPResources(ctx, args.Server)
+	case readResourceTool.Function.Name:
+		var args struct {
+			Server string `json:"server"`
+			URI    string `json:"uri"`
+		}
+		if err := unmarshalArguments(argumentsJSON, &args); err != nil {
+			return "", err
+		}
 
-			// Store call end information to be printed later (after LLM response)
-			info := MCPToolInfo{
-				ToolName:  mcpToolName,
-				Arguments: mcpArgs,
-				StartTime: startTime,
-				EndTime:   time.Now(),
-			}
-			a.pendingMCPCallEnding = append(a.pendingMCPCallEnding, info)
+		result, err := a.MCP.ReadResource(ctx, args.Server, args.URI)
+		if err != nil {
+			return "", err
+		}
+		return result.Text(), nil
+	case readToolOutputTool.Function.Name:
+		var args struct {
+			ID      string `json:"id"`
+			Offset  int    `json:"offset"`
+			Limit   int    `json:"limit"`
+			Pattern string `json:"pattern"`
+		}
+		if err := unmarshalArguments(argumentsJSON, &args); err != nil {
+			return "", err
+		}
 
-			return result, err
+		return a.readToolOutput(args.ID, args.Offset, args.Limit, args.Pattern)
+	case loadSkillTool.Function.Name:
+		var args struct {
+			Name      string `json:"name"`
+			Reference string `json:"reference"`
 		}
+		if err := unmarshalArguments(argumentsJSON, &args); err != nil {
+			return "", err
+		}
+
+		return a.loadSkill(args.Name, args.Reference)
+	}
 
-		// Non-MCP command, execute normally
-		return executeBashCommand(args.Command)
-	default:
+	server, serverTool, ok := a.MCP.Lookup(toolName)
+	if !ok {
 		return "", fmt.Errorf("unknown tool: %s", toolName)
 	}
+
+	args := map[string]interface{}{}
+	if err := unmarshalArguments(argumentsJSON, &args); err != nil {
+		return "", err
+	}
+
+	// Output call start information
+	fmt.Print(a.redact(formatMCPCallStart(callLabel(ctx), server, serverTool, args)))
+
+	// Record start time and execute
+	startTime := time.Now()
+	result, err := a.callMCPTool(ctx, toolName, args)
+
+	// Store call end information to be printed later (after LLM response)
+	info := MCPToolInfo{
+		Server:    server,
+		ToolName:  serverTool,
+		Arguments: args,
+		StartTime: startTime,
+		EndTime:   time.Now(),
+	}
+	a.mu.Lock()
+	a.pendingMCPCallEnding = append(a.pendingMCPCallEnding, info)
+	a.mu.Unlock()
+
+	return result, err
+}
+
+// unmarshalArguments decodes tool call arguments; empty arguments leave v unchanged
+func unmarshalArguments(argumentsJSON string, v interface{}) error {
+	if strings.TrimSpace(argumentsJSON) == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(argumentsJSON), v); err != nil {
+		return fmt.Errorf("failed to parse arguments: %w", err)
+	}
+	return nil
 }
 
-// executeBashCommand runs a bash command and returns stdout
-func executeBashCommand(command string) (string, error) {
-	// Use bash -c to execute the command
-	cmd := exec.Command("bash", "-c", command)
+// listMCPResources returns the resources of the MCP servers as indented JSON
+func (a *Assistant) listMCPResources(ctx context.Context, server string) (string, error) {
+	resources, err := a.MCP.ListResources(ctx, server)
+	if err != nil {
+		return "", err
+	}
 
-	output, err := cmd.CombinedOutput()
+	data, err := json.MarshalIndent(map[string]interface{}{"resources": resources}, "", "  ")
 	if err != nil {
-		return string(output), fmt.Errorf("command failed: %w\nOutput: %s", err, string(output))
+		return "", fmt.Errorf("failed to format resource list: %w", err)
 	}
+	return string(data), nil
+}
 
-	return strings.TrimSpace(string(output)), nil
+// callMCPTool invokes a tool on the MCP server providing it and returns its text content
+func (a *Assistant) callMCPTool(ctx context.Context, toolName string, args map[string]interface{}) (string, error) {
+	result, err := a.MCP.CallTool(ctx, toolName, args, func(n mcp.Notice) {
+		fmt.Print(a.redact(formatMCPNotice(callLabel(ctx), n)))
+	})
+	if err != nil {
+		return "", err
+	}
+
+	text := result.Text()
+	if result.IsError {
+		return text, fmt.Errorf("tool %s returned an error: %s", toolName, text)
+	}
+
+	return text, nil
+}
+
+// cnbEnv returns the CNB settings exported to local commands, so scripts
+// such as cnb-mcp.py talk to the same endpoint. The token is only exported
+// when the sandbox profile lists CNB_TOKEN in env.
+func (a *Assistant) cnbEnv() []string {
+	env := []string{
+		"CNB_MCP_URL=" + a.Config.CNB.MCPURL,
+		"CNB_MCP_TRANSPORT=" + a.Config.CNB.Transport,
+		"CNB_API_BASE=" + a.Config.CNB.APIBase,
+	}
+	if a.Sandbox.PassesEnv("CNB_TOKEN") {
+		env = append(env, "CNB_TOKEN="+a.Config.CNB.Token)
+	}
+	return env
 }
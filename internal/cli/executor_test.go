@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"slices"
+	"testing"
+
+	"cnb.cool/znb/learn-skills/internal/config"
+	"cnb.cool/znb/learn-skills/internal/sandbox"
+)
+
+func TestCNBEnvExportsTokenOnlyWhenPassed(t *testing.T) {
+	for _, name := range []string{"default", "strict"} {
+		profile, _ := config.SandboxConfig{Profile: name}.ActiveProfile()
+		box, err := sandbox.New(name, profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a := newTestAssistant(t, nil, nil)
+		a.Config.CNB = config.CNBConfig{Token: "cnb-token-4f9a8b7c6d5e", MCPURL: "https://mcp.cnb.cool/sse"}
+		a.Sandbox = box
+
+		env := a.cnbEnv()
+		if !slices.Contains(env, "CNB_MCP_URL=https://mcp.cnb.cool/sse") {
+			t.Errorf("%s: expected CNB_MCP_URL exported, got %q", name, env)
+		}
+		if got, want := slices.Contains(env, "CNB_TOKEN=cnb-token-4f9a8b7c6d5e"), name == "default"; got != want {
+			t.Errorf("%s: CNB_TOKEN exported = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -2,8 +2,12 @@ package cli
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"strings"
 )
 
@@ -11,18 +15,54 @@ import (
 func RunInteractive(assistant *Assistant) error {
 	fmt.Println("CNB Assistant - Interactive Mode")
 	fmt.Println("Type 'exit' to quit, 'clear' to reset conversation, 'help' for help")
+	fmt.Println("Press Ctrl+C to cancel a running request")
+	if assistant.Policy != nil && assistant.Policy.ReadOnly {
+		fmt.Println("🔒 Read-only mode: tools that change CNB state are disabled")
+	}
+	if assistant.Session != nil {
+		fmt.Printf("Session: %s (resume later with /resume or --resume)\n", assistant.Session.ID)
+	}
 	fmt.Println()
 
-	scanner := bufio.NewScanner(os.Stdin)
+	reader := newLineReader(os.Stdin)
+	if assistant.Approve == nil {
+		assistant.Approve = terminalApprover(reader, assistant.Redactor)
+	}
 
 	for {
 		fmt.Print("CNB Assistant> ")
 
-		if !scanner.Scan() {
+		line, err := reader.ReadLine(context.Background())
+		if errors.Is(err, io.EOF) {
 			break
 		}
+		if err != nil {
+			return fmt.Errorf("scanner error: %w", err)
+		}
+
+		input := strings.TrimSpace(line)
 
-		input := strings.TrimSpace(scanner.Text())
+		// Slash commands list MCP prompts/resources or expand a prompt into the message
+		if strings.HasPrefix(input, "/") {
+			var expanded string
+			err := withInterrupt(func(ctx context.Context) error {
+				var err error
+				expanded, err = handleSlashCommand(ctx, assistant, input)
+				return err
+			})
+			if errors.Is(err, context.Canceled) {
+				fmt.Println("\n⚠️  已取消当前请求")
+				continue
+			}
+			if err != nil {
+				fmt.Printf("Error: %s\n", assistant.redact(err.Error()))
+				continue
+			}
+			if expanded == "" {
+				continue
+			}
+			input = expanded
+		}
 
 		// Handle special commands
 		switch input {
@@ -32,6 +72,9 @@ func RunInteractive(assistant *Assistant) error {
 		case "clear":
 			assistant.Reset()
 			fmt.Println("Conversation cleared.")
+			if assistant.Session != nil {
+				fmt.Printf("New session: %s\n", assistant.Session.ID)
+			}
 			continue
 		case "help":
 			printHelp()
@@ -42,12 +85,20 @@ func RunInteractive(assistant *Assistant) error {
 
 		// Process user message with streaming
 		fmt.Println()
-		_, err := assistant.ProcessMessageStream(input, func(chunk string) error {
-			fmt.Print(chunk)
-			return nil
+		err = withInterrupt(func(ctx context.Context) error {
+			_, err := assistant.ProcessMessageStream(ctx, input, func(chunk string) error {
+				fmt.Print(chunk)
+				return nil
+			})
+			return err
 		})
+		if errors.Is(err, context.Canceled) {
+			// The partial turn was rolled back; the conversation continues
+			fmt.Println("\n⚠️  已取消当前请求")
+			continue
+		}
 		if err != nil {
-			fmt.Printf("\nError: %v\n", err)
+			fmt.Printf("\nError: %s\n", assistant.redact(err.Error()))
 			continue
 		}
 
@@ -55,19 +106,88 @@ func RunInteractive(assistant *Assistant) error {
 		fmt.Println()
 	}
 
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("scanner error: %w", err)
+	return nil
+}
+
+// lineReader reads input lines in the background so that a prompt waiting
+// for an answer can be abandoned with Ctrl+C without losing the next line
+type lineReader struct {
+	lines chan string
+	err   error // Set before lines is closed
+}
+
+// newLineReader starts reading lines from r
+func newLineReader(r io.Reader) *lineReader {
+	lr := &lineReader{lines: make(chan string)}
+	go func() {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lr.lines <- scanner.Text()
+		}
+		lr.err = scanner.Err()
+		close(lr.lines)
+	}()
+	return lr
+}
+
+// ReadLine waits for the next line. It returns io.EOF at the end of input
+// and ctx.Err() when ctx is done first.
+func (lr *lineReader) ReadLine(ctx context.Context) (string, error) {
+	select {
+	case line, ok := <-lr.lines:
+		if !ok {
+			if lr.err != nil {
+				return "", lr.err
+			}
+			return "", io.EOF
+		}
+		return line, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
 	}
+}
 
-	return nil
+// withInterrupt runs fn with a context that is cancelled by Ctrl+C.
+// SIGINT is only intercepted while fn runs, so Ctrl+C at the prompt still exits.
+func withInterrupt(fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err := fn(ctx)
+	if err != nil && ctx.Err() != nil {
+		return context.Canceled
+	}
+	return err
 }
 
 func printHelp() {
-	fmt.Println(`
+	fmt.Print(`
 Available Commands:
   exit, quit  - Exit the assistant
   clear       - Clear conversation history
   help        - Show this help message
+  Ctrl+C      - Cancel the running request (press at the prompt to exit)
+  y / n / a   - Answer an approval prompt: run once, refuse, or always run that tool
+  /sessions   - List saved sessions
+  /resume <id|last>
+              - Resume a saved session (a unique ID prefix is enough)
+  /prompts    - List prompts offered by the MCP servers
+  /resources [server]
+              - List resources offered by the MCP servers
+  /<prompt> [key=value ...]
+              - Run an MCP prompt (use /<server>:<prompt> if ambiguous)
 
 You can ask questions like:
   - "List my repositories"
@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"cnb.cool/znb/learn-skills/internal/llm"
+)
+
+// wrapUpInstruction asks the model to answer without tools once the tool loop is stopped
+const wrapUpInstruction = `%s
+Do not call any more tools. Answer the user now with what the tool results above show: what you found, what is still unknown, and what the user could check next.`
+
+// runToolLoop sends the history to the LLM and runs the tools it calls until
+// it answers, streaming to callback when it is not nil. After
+// tools.max_iterations rounds, or when the model keeps making the same call,
+// the model is asked for a final answer without tools.
+func (a *Assistant) runToolLoop(ctx context.Context, tools []llm.Tool, callback llm.StreamCallback) (string, error) {
+	guard := newLoopGuard(a.Config.Tools.MaxRepeats)
+	reason := fmt.Sprintf("The tool call limit of %d rounds for this request was reached.", a.Config.Tools.MaxIterations)
+
+	for i := 0; i < a.Config.Tools.MaxIterations; i++ {
+		resp, err := a.chat(ctx, tools, callback)
+		if err != nil {
+			return "", err
+		}
+
+		if len(resp.Choices) == 0 {
+			return "", fmt.Errorf("no response from LLM")
+		}
+
+		assistantMsg := resp.Choices[0].Message
+		assistantMsg.Content = a.redact(assistantMsg.Content)
+		finishReason := resp.Choices[0].FinishReason
+
+		// Add assistant response to history
+		a.Messages = append(a.Messages, assistantMsg)
+
+		// LLM finished (no more tool calls)
+		if finishReason != "tool_calls" || len(assistantMsg.ToolCalls) == 0 {
+			// Print any pending MCP call ending info and persist the turn
+			a.finishTurn()
+			return assistantMsg.Content, nil
+		}
+
+		if repeated := guard.repeated(assistantMsg.ToolCalls); repeated != "" {
+			// Every call still needs a result for the history to stay valid
+			for _, toolCall := range assistantMsg.ToolCalls {
+				a.Messages = append(a.Messages, llm.Message{
+					Role: "tool",
+					Content: toolErrorContent(toolCall.Function.Name, "repeated",
+						"not run: the same call was already made earlier in this request; use its earlier result", ""),
+					ToolCallID: toolCall.ID,
+				})
+			}
+			reason = fmt.Sprintf("You called %s with the same arguments %d times, so the tool loop was stopped.", repeated, guard.limit+1)
+			break
+		}
+
+		if err := a.executeToolCalls(ctx, assistantMsg.ToolCalls); err != nil {
+			return "", err
+		}
+	}
+
+	return a.wrapUp(ctx, reason, callback)
+}
+
+// wrapUp asks the model, without tools, to answer from the results gathered
+// so far after the tool loop was stopped for reason
+func (a *Assistant) wrapUp(ctx context.Context, reason string, callback llm.StreamCallback) (string, error) {
+	fmt.Fprintf(os.Stderr, "\n⚠️  %s\n", reason)
+
+	a.Messages = append(a.Messages, llm.Message{
+		Role:    "system",
+		Content: fmt.Sprintf(wrapUpInstruction, reason),
+	})
+
+	resp, err := a.chat(ctx, nil, callback)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no response from LLM")
+	}
+
+	// Without tools on offer any tool calls are dropped so the history stays valid
+	assistantMsg := resp.Choices[0].Message
+	assistantMsg.Content = a.redact(assistantMsg.Content)
+	assistantMsg.ToolCalls = nil
+	a.Messages = append(a.Messages, assistantMsg)
+
+	a.finishTurn()
+	return assistantMsg.Content, nil
+}
+
+// loopGuard counts the tool calls of one turn to catch a model that keeps
+// making the same call
+type loopGuard struct {
+	limit int            // How often an identical call may run
+	seen  map[string]int // Runs per tool name and arguments
+}
+
+// newLoopGuard returns a guard that allows each identical call limit times
+func newLoopGuard(limit int) *loopGuard {
+	return &loopGuard{limit: limit, seen: map[string]int{}}
+}
+
+// repeated records calls and returns the name of the first tool called with
+// the same arguments more than limit times, "" if there is none
+func (g *loopGuard) repeated(calls []llm.ToolCall) string {
+	name := ""
+	for _, call := range calls {
+		key := call.Function.Name + "\x00" + strings.TrimSpace(call.Function.Arguments)
+		g.seen[key]++
+		if g.seen[key] > g.limit && name == "" {
+			name = call.Function.Name
+		}
+	}
+	return name
+}
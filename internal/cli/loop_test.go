@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"cnb.cool/znb/learn-skills/internal/llm"
+)
+
+// workTool is how the fake "work" tool is offered to the LLM
+var workTool = llm.Tool{Type: "function", Function: llm.Function{Name: "work", Description: "Do some work.", Parameters: map[string]interface{}{"type": "object"}}}
+
+func TestToolLoopStopsAfterMaxIterations(t *testing.T) {
+	fake, client := newFakeLLM(t, func(req chatRequest) llm.Message {
+		if len(req.Tools) == 0 {
+			return llm.Message{Content: "Done."}
+		}
+		// A new call every round so only the iteration limit stops the loop
+		n := len(req.Messages)
+		return llm.Message{ToolCalls: []llm.ToolCall{toolCall(fmt.Sprintf("c%d", n), "work", fmt.Sprintf(`{"n":%d}`, n))}}
+	})
+	var runs atomic.Int32
+	a := newTestAssistant(t, client, nil)
+	a.Config.Tools.MaxIterations = 3
+	withFakeTools(t, a, &fakeTools{handle: func(ctx context.Context, n int) error {
+		runs.Add(1)
+		return nil
+	}})
+
+	answer, err := a.runToolLoop(context.Background(), []llm.Tool{workTool}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != "Done." {
+		t.Errorf("Expected the wrap-up answer, got %q", answer)
+	}
+	if got := runs.Load(); got != 3 {
+		t.Errorf("Expected 3 tool runs, got %d", got)
+	}
+
+	requests := fake.Requests()
+	if len(requests) != 4 {
+		t.Fatalf("Expected 3 rounds and a final call, got %d requests", len(requests))
+	}
+	last := requests[3]
+	if len(last.Tools) != 0 {
+		t.Errorf("Expected the final call without tools, got %d tools", len(last.Tools))
+	}
+	if instruction := last.Messages[len(last.Messages)-1]; instruction.Role != "system" || !strings.Contains(instruction.Content, "limit of 3 rounds") {
+		t.Errorf("Expected the wrap-up instruction last, got %+v", instruction)
+	}
+}
+
+func TestToolLoopStopsRepeatedCalls(t *testing.T) {
+	fake, client := newFakeLLM(t, func(req chatRequest) llm.Message {
+		if len(req.Tools) == 0 {
+			return llm.Message{Content: "Done."}
+		}
+		n := len(req.Messages)
+		return llm.Message{ToolCalls: []llm.ToolCall{toolCall(fmt.Sprintf("c%d", n), "work", `{"n":1}`)}}
+	})
+	var runs atomic.Int32
+	a := newTestAssistant(t, client, nil)
+	a.Config.Tools.MaxRepeats = 2
+	withFakeTools(t, a, &fakeTools{handle: func(ctx context.Context, n int) error {
+		runs.Add(1)
+		return nil
+	}})
+
+	answer, err := a.runToolLoop(context.Background(), []llm.Tool{workTool}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != "Done." {
+		t.Errorf("Expected the wrap-up answer, got %q", answer)
+	}
+	if got := runs.Load(); got != 2 {
+		t.Errorf("Expected the call to run 2 times, got %d", got)
+	}
+
+	requests := fake.Requests()
+	if len(requests) != 4 || len(requests[3].Tools) != 0 {
+		t.Fatalf("Expected 3 rounds and a final call without tools, got %d requests", len(requests))
+	}
+	final := requests[3].Messages
+	refused := final[len(final)-2]
+	if refused.Role != "tool" || !strings.Contains(refused.Content, `"type":"repeated"`) {
+		t.Errorf("Expected a repeated tool error for the third call, got %+v", refused)
+	}
+	if instruction := final[len(final)-1]; instruction.Role != "system" || !strings.Contains(instruction.Content, "same arguments 3 times") {
+		t.Errorf("Expected the wrap-up instruction last, got %+v", instruction)
+	}
+}
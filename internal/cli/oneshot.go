@@ -1,7 +1,10 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
 	"strings"
 )
 
@@ -14,14 +17,23 @@ func RunOneShot(assistant *Assistant, args []string) error {
 		return fmt.Errorf("no query provided")
 	}
 
+	// Ctrl+C aborts the in-flight LLM or tool call instead of killing the process mid-request
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	// Process the query
-	response, err := assistant.ProcessMessage(query)
+	response, err := assistant.ProcessMessage(ctx, query)
 	if err != nil {
-		return fmt.Errorf("failed to process query: %w", err)
+		return fmt.Errorf("failed to process query: %s", assistant.redact(err.Error()))
 	}
 
 	// Print response
 	fmt.Println(response)
 
+	// Tell the user how to continue; stderr keeps stdout clean for scripts
+	if assistant.Session != nil {
+		fmt.Fprintf(os.Stderr, "\nSession %s saved, continue it with --resume %s\n", assistant.Session.ID, assistant.Session.ID)
+	}
+
 	return nil
 }
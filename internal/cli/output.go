@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"cnb.cool/znb/learn-skills/internal/artifact"
+)
+
+// Defaults for read_tool_output when the model omits them
+const (
+	defaultReadLines   = 200
+	maxGrepMatches     = 100
+	maxReadLineLength  = 500
+	excerptNoticeBytes = 512 // Room left in the limit for the notice around an excerpt
+)
+
+// limitToolOutput returns content unchanged when it fits tool_output.max_bytes.
+// Larger content is stored as an artifact and replaced by a head/tail excerpt
+// telling the model how to read the rest with read_tool_output.
+func (a *Assistant) limitToolOutput(toolName, content string) string {
+	limit := a.Config.ToolOutput.MaxBytes
+	if limit <= 0 || len(content) <= limit {
+		return content
+	}
+
+	head, tail := artifact.HeadTail(content, max((limit-excerptNoticeBytes)/2, 1))
+
+	var sb strings.Builder
+	var stored *artifact.Artifact
+	if a.Artifacts != nil {
+		var err error
+		stored, err = a.Artifacts.Save(toolName, content)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  保存工具输出失败，已截断：%s\n", a.redact(err.Error()))
+		}
+	}
+
+	if stored != nil {
+		fmt.Printf("   📦 %s 输出过大（%d 字节），已保存到 %s\n", toolName, stored.Size, stored.Path)
+		fmt.Fprintf(&sb, "[Output of %s is %d bytes (%d lines), more than the %d-byte limit. "+
+			"The full output is stored as artifact %q; call read_tool_output with this id "+
+			"to read a line range or grep it with a pattern.]\n",
+			toolName, stored.Size, stored.Lines, limit, stored.ID)
+	} else {
+		fmt.Fprintf(&sb, "[Output of %s is %d bytes, more than the %d-byte limit, and was truncated. "+
+			"Only the beginning and end are shown.]\n", toolName, len(content), limit)
+	}
+
+	sb.WriteString("\n--- beginning of output ---\n")
+	sb.WriteString(head)
+	sb.WriteString("\n--- end of output ---\n")
+	sb.WriteString(tail)
+	return sb.String()
+}
+
+// readToolOutput serves read_tool_output: a range of lines of a stored
+// artifact, or the lines matching pattern. The reply stays within the
+// tool output limit so it is never spilled itself.
+func (a *Assistant) readToolOutput(id string, offset, limit int, pattern string) (string, error) {
+	if a.Artifacts == nil {
+		return "", fmt.Errorf("no stored tool outputs")
+	}
+	if id == "" {
+		return "", fmt.Errorf("id is required")
+	}
+
+	var sb strings.Builder
+	if pattern != "" {
+		matches, err := a.Artifacts.Grep(id, pattern, maxGrepMatches)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sb, "%d matching lines", len(matches))
+		if len(matches) == maxGrepMatches {
+			sb.WriteString(" (stopped at the limit, narrow the pattern to see more)")
+		}
+		sb.WriteString(":\n")
+		for _, m := range matches {
+			fmt.Fprintf(&sb, "%d: %s\n", m.Line, clipText(m.Text, maxReadLineLength))
+		}
+	} else {
+		if limit <= 0 {
+			limit = defaultReadLines
+		}
+		page, err := a.Artifacts.ReadLines(id, offset, limit)
+		if err != nil {
+			return "", err
+		}
+		last := page.Offset + len(page.Lines) - 1
+		if len(page.Lines) == 0 {
+			fmt.Fprintf(&sb, "No lines at offset %d; the output has %d lines.\n", page.Offset, page.TotalLines)
+		} else {
+			fmt.Fprintf(&sb, "Lines %d-%d of %d:\n", page.Offset, last, page.TotalLines)
+		}
+		for i, line := range page.Lines {
+			fmt.Fprintf(&sb, "%d: %s\n", page.Offset+i, clipText(line, maxReadLineLength))
+		}
+	}
+
+	out := sb.String()
+	if maxBytes := a.Config.ToolOutput.MaxBytes; maxBytes > 0 && len(out) > maxBytes {
+		cut := strings.LastIndexByte(out[:maxBytes], '\n')
+		if cut < 0 {
+			cut = maxBytes
+		}
+		out = out[:cut] + "\n[Reply cut at the tool output limit; request fewer lines.]"
+	}
+	return out, nil
+}
@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"cnb.cool/znb/learn-skills/internal/artifact"
+)
+
+// artifactRef finds the artifact id limitToolOutput hands to the model
+var artifactRef = regexp.MustCompile(`stored as artifact "([0-9a-z-]+)"`)
+
+// numberedLines returns n lines "line 1: build log" ... "line n: build log"
+func numberedLines(n int) string {
+	var sb strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&sb, "line %d: build log\n", i)
+	}
+	return sb.String()
+}
+
+func TestLimitToolOutput(t *testing.T) {
+	a := newTestAssistant(t, nil, nil)
+	a.Config.ToolOutput.MaxBytes = 2048
+	a.Artifacts = artifact.NewStore(t.TempDir())
+
+	small := numberedLines(10)
+	if got := a.limitToolOutput("work", small); got != small {
+		t.Errorf("Expected output within the limit unchanged, got %q", got)
+	}
+
+	content := numberedLines(1000)
+	got := a.limitToolOutput("work", content)
+	if len(got) > a.Config.ToolOutput.MaxBytes {
+		t.Errorf("Expected the excerpt within %d bytes, got %d", a.Config.ToolOutput.MaxBytes, len(got))
+	}
+	if !strings.Contains(got, "line 1: build log") || !strings.Contains(got, "line 1000: build log") {
+		t.Errorf("Expected the head and tail of the output, got %q", got)
+	}
+	if !strings.Contains(got, "(1000 lines)") {
+		t.Errorf("Expected the line count in the notice, got %q", got)
+	}
+
+	m := artifactRef.FindStringSubmatch(got)
+	if m == nil {
+		t.Fatalf("Expected an artifact id in the excerpt, got %q", got)
+	}
+	page, err := a.Artifacts.ReadLines(m[1], 1, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(page.Lines, "\n")+"\n" != content {
+		t.Error("Expected the full output stored in the artifact")
+	}
+}
+
+func TestLimitToolOutputWithoutStore(t *testing.T) {
+	a := newTestAssistant(t, nil, nil)
+	a.Config.ToolOutput.MaxBytes = 2048
+
+	got := a.limitToolOutput("work", numberedLines(1000))
+	if !strings.Contains(got, "was truncated") || artifactRef.MatchString(got) {
+		t.Errorf("Expected a truncation notice without an artifact id, got %q", got)
+	}
+}
+
+func TestReadToolOutput(t *testing.T) {
+	a := newTestAssistant(t, nil, nil)
+	a.Config.ToolOutput.MaxBytes = 2048
+	a.Artifacts = artifact.NewStore(t.TempDir())
+	stored, err := a.Artifacts.Save("work", numberedLines(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		args    string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "page",
+			args:    fmt.Sprintf(`{"id":%q,"offset":10,"limit":3}`, stored.ID),
+			want:    []string{"Lines 10-12 of 1000:\n10: line 10: build log\n11: line 11: build log\n12: line 12: build log\n"},
+			notWant: []string{"line 13:"},
+		},
+		{
+			name: "last page",
+			args: fmt.Sprintf(`{"id":%q,"offset":999,"limit":5}`, stored.ID),
+			want: []string{"Lines 999-1000 of 1000:"},
+		},
+		{
+			name: "past the end",
+			args: fmt.Sprintf(`{"id":%q,"offset":2000}`, stored.ID),
+			want: []string{"No lines at offset 2000; the output has 1000 lines."},
+		},
+		{
+			name:    "pattern",
+			args:    fmt.Sprintf(`{"id":%q,"pattern":"^line 99[0-9]:"}`, stored.ID),
+			want:    []string{"10 matching lines:\n990: line 990: build log\n", "999: line 999: build log\n"},
+			notWant: []string{"1000:"},
+		},
+		{
+			name: "pattern stops at the match limit",
+			args: fmt.Sprintf(`{"id":%q,"pattern":"build"}`, stored.ID),
+			want: []string{"100 matching lines (stopped at the limit"},
+		},
+		{
+			name:    "reply capped at the output limit",
+			args:    fmt.Sprintf(`{"id":%q,"limit":1000}`, stored.ID),
+			want:    []string{"Lines 1-1000 of 1000:", "\n[Reply cut at the tool output limit; request fewer lines.]"},
+			notWant: []string{"line 1000:"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := a.ExecuteTool(context.Background(), readToolOutputTool.Function.Name, tc.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if limit := a.Config.ToolOutput.MaxBytes + 100; len(got) > limit {
+				t.Errorf("Expected the reply within %d bytes, got %d", limit, len(got))
+			}
+			for _, w := range tc.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("Expected %q in the reply, got %q", w, got)
+				}
+			}
+			for _, w := range tc.notWant {
+				if strings.Contains(got, w) {
+					t.Errorf("Expected no %q in the reply, got %q", w, got)
+				}
+			}
+		})
+	}
+}
+
+func TestReadToolOutputUnknownID(t *testing.T) {
+	a := newTestAssistant(t, nil, nil)
+	a.Artifacts = artifact.NewStore(t.TempDir())
+
+	for _, id := range []string{"20260101-000000-work-missing", "../config"} {
+		if _, err := a.readToolOutput(id, 1, 10, ""); !errors.Is(err, artifact.ErrNotFound) {
+			t.Errorf("id %q: expected ErrNotFound, got %v", id, err)
+		}
+		if _, err := a.readToolOutput(id, 0, 0, "build"); !errors.Is(err, artifact.ErrNotFound) {
+			t.Errorf("id %q with a pattern: expected ErrNotFound, got %v", id, err)
+		}
+	}
+	if _, err := a.readToolOutput("", 1, 10, ""); err == nil {
+		t.Error("Expected an error without an id")
+	}
+}
@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"cnb.cool/znb/learn-skills/internal/llm"
+	"cnb.cool/znb/learn-skills/internal/session"
+)
+
+// finishTurn prints the MCP call summaries of a completed turn and saves the session
+func (a *Assistant) finishTurn() {
+	if a.Session != nil {
+		for _, info := range a.pendingMCPCallEnding {
+			info.Arguments = a.redactArguments(info.Arguments)
+			a.Session.MCPCalls = append(a.Session.MCPCalls, session.MCPCall(info))
+		}
+	}
+	a.printPendingMCPCallEndings()
+
+	if err := a.SaveSession(); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  保存会话失败：%v\n", err)
+	}
+}
+
+// redactArguments returns a copy of tool arguments with secrets masked in every string value
+func (a *Assistant) redactArguments(args map[string]interface{}) map[string]interface{} {
+	if args == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		out[k] = a.redactValue(v)
+	}
+	return out
+}
+
+// redactToolCalls returns msg with secrets masked in the arguments of its tool calls
+func (a *Assistant) redactToolCalls(msg llm.Message) llm.Message {
+	if len(msg.ToolCalls) == 0 {
+		return msg
+	}
+	calls := make([]llm.ToolCall, len(msg.ToolCalls))
+	for i, call := range msg.ToolCalls {
+		var args map[string]interface{}
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err == nil && args != nil {
+			if data, err := json.Marshal(a.redactArguments(args)); err == nil {
+				call.Function.Arguments = string(data)
+			}
+		} else {
+			call.Function.Arguments = a.redact(call.Function.Arguments)
+		}
+		calls[i] = call
+	}
+	msg.ToolCalls = calls
+	return msg
+}
+
+// redactValue masks secrets in a decoded JSON value
+func (a *Assistant) redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return a.redact(v)
+	case map[string]interface{}:
+		return a.redactArguments(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = a.redactValue(item)
+		}
+		return out
+	}
+	return v
+}
+
+// SaveSession writes the current conversation to the session store
+func (a *Assistant) SaveSession() error {
+	if a.Sessions == nil || a.Session == nil {
+		return nil
+	}
+	// The system prompt is not stored; a resumed session uses the current skill
+	a.Session.Messages = make([]llm.Message, 0, len(a.Messages)-1)
+	for _, msg := range a.Messages[1:] {
+		a.Session.Messages = append(a.Session.Messages, a.redactToolCalls(msg))
+	}
+	a.Session.Skills = append([]string(nil), a.loadedSkills...)
+	return a.Sessions.Save(a.Session)
+}
+
+// ResumeSession replaces the conversation with a stored session.
+// id may be a full ID, a unique prefix or session.Latest.
+func (a *Assistant) ResumeSession(id string) error {
+	if a.Sessions == nil {
+		return fmt.Errorf("sessions are disabled")
+	}
+
+	s, err := a.Sessions.Load(id)
+	if err != nil {
+		return err
+	}
+
+	systemMsg := a.Messages[0]
+	a.Messages = append([]llm.Message{systemMsg}, s.Messages...)
+	a.Session = s
+	a.pendingMCPCallEnding = nil
+
+	// Skills removed since the session was saved are no longer loaded
+	a.loadedSkills = nil
+	for _, name := range s.Skills {
+		if _, ok := a.Skills.Get(name); ok {
+			a.loadedSkills = append(a.loadedSkills, name)
+		}
+	}
+	return nil
+}
+
+// PrintSessions lists the stored sessions, most recent first
+func PrintSessions(store *session.Store) error {
+	sessions, err := store.List()
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		fmt.Println("No saved sessions.")
+		return nil
+	}
+
+	fmt.Printf("Saved sessions (%s):\n", store.Dir())
+	for _, s := range sessions {
+		fmt.Printf("  %s  %s  %3d messages  %s\n",
+			s.ID, s.UpdatedAt.Format("2006-01-02 15:04"), len(s.Messages), s.Title)
+	}
+	return nil
+}
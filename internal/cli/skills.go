@@ -0,0 +1,456 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"cnb.cool/znb/learn-skills/internal/llm"
+	"cnb.cool/znb/learn-skills/internal/mcp"
+	"cnb.cool/znb/learn-skills/internal/policy"
+	"cnb.cool/znb/learn-skills/internal/skill"
+)
+
+// skillCatalogIntro opens the system prompt; the skills' instructions are
+// only sent when the model loads them
+const skillCatalogIntro = `You are an assistant for CNB (Cloud Native Build), a cloud-native development platform.
+Your instructions are organized as skills. Only their names and descriptions are listed below.
+Before working on a task a skill covers, call load_skill with its name and follow the instructions it returns.
+Load only the skills the request needs; a skill already loaded in this conversation does not need to be loaded again.
+
+Available skills:`
+
+// systemPrompt lists the name and description of every skill
+func (a *Assistant) systemPrompt() string {
+	var sb strings.Builder
+	sb.WriteString(skillCatalogIntro)
+	for _, s := range a.Skills.All() {
+		fmt.Fprintf(&sb, "\n- %s: %s", s.Name, strings.Join(strings.Fields(s.Description), " "))
+	}
+	return sb.String()
+}
+
+// loadSkill serves load_skill: the instructions of a skill, or one of its
+// reference documents when reference is set. Loading marks the skill as
+// loaded for the rest of the session.
+func (a *Assistant) loadSkill(name, reference string) (string, error) {
+	s, ok := a.Skills.Get(name)
+	if !ok {
+		var names []string
+		for _, s := range a.Skills.All() {
+			names = append(names, s.Name)
+		}
+		return "", fmt.Errorf("unknown skill %q (available: %s)", name, strings.Join(names, ", "))
+	}
+
+	if reference != "" {
+		if !slices.Contains(s.References, reference) {
+			return "", fmt.Errorf("skill %s has no reference %q (available: %s)", name, reference, strings.Join(s.References, ", "))
+		}
+		data, err := os.ReadFile(filepath.Join(s.Dir, reference))
+		if err != nil {
+			return "", fmt.Errorf("failed to read reference: %w", err)
+		}
+		return string(data), nil
+	}
+
+	a.mu.Lock()
+	if !slices.Contains(a.loadedSkills, name) {
+		a.loadedSkills = append(a.loadedSkills, name)
+	}
+	a.mu.Unlock()
+
+	return skillInstructions(s), nil
+}
+
+// skillInstructions is what load_skill returns for a skill: its body and
+// where its files are
+func skillInstructions(s *skill.Skill) string {
+	var sb strings.Builder
+	sb.WriteString(s.Body)
+	fmt.Fprintf(&sb, "\n\nThe files of this skill are in %s", s.Dir)
+	if len(s.Scripts) > 0 {
+		fmt.Fprintf(&sb, "; its scripts are %s, relative to that directory", strings.Join(s.Scripts, ", "))
+	}
+	sb.WriteString(".")
+	if len(s.References) > 0 {
+		fmt.Fprintf(&sb, "\n\nReference documents of this skill, readable with load_skill and reference: %s", strings.Join(s.References, ", "))
+	}
+	return sb.String()
+}
+
+// skillPrefix starts a system message that carries a loaded skill's
+// instructions after the load_skill result was compacted away; the skill's
+// name and "]" follow
+const skillPrefix = "[Instructions of loaded skill "
+
+// keptSkillMessages returns a system message with the instructions of each
+// loaded skill whose load_skill result is not among kept, so compacting the
+// history never takes a loaded skill's instructions away from the model
+func (a *Assistant) keptSkillMessages(kept []llm.Message) []llm.Message {
+	present := map[string]bool{}
+	loads := map[string]string{} // load_skill call ID to skill name
+	for _, msg := range kept {
+		switch msg.Role {
+		case "assistant":
+			for _, call := range msg.ToolCalls {
+				var args struct {
+					Name      string `json:"name"`
+					Reference string `json:"reference"`
+				}
+				if call.Function.Name == loadSkillTool.Function.Name &&
+					json.Unmarshal([]byte(call.Function.Arguments), &args) == nil && args.Reference == "" {
+					loads[call.ID] = args.Name
+				}
+			}
+		case "tool":
+			if name, ok := loads[msg.ToolCallID]; ok {
+				present[name] = true
+			}
+		case "system":
+			if rest, ok := strings.CutPrefix(msg.Content, skillPrefix); ok {
+				name, _, _ := strings.Cut(rest, "]")
+				present[name] = true
+			}
+		}
+	}
+
+	var messages []llm.Message
+	for _, name := range a.loadedSkills {
+		s, ok := a.Skills.Get(name)
+		if !ok || present[name] {
+			continue
+		}
+		messages = append(messages, llm.Message{
+			Role:    "system",
+			Content: skillPrefix + name + "]\n" + a.redact(skillInstructions(s)),
+		})
+	}
+	return messages
+}
+
+// checkSkillTools refuses a tool call that none of the loaded skills allows.
+// Only skills that declare allowed-tools restrict calls: with any loaded
+// skill that does not, or none loaded, every tool may be called. Loading
+// skills and reading stored tool output are always allowed.
+func (a *Assistant) checkSkillTools(toolName string) error {
+	if a.Skills == nil || toolName == loadSkillTool.Function.Name || toolName == readToolOutputTool.Function.Name {
+		return nil
+	}
+
+	names := []string{toolName}
+	if server, tool, ok := a.MCP.Lookup(toolName); ok {
+		names = append(names, server+"/"+tool)
+	}
+
+	a.mu.Lock()
+	loaded := slices.Clone(a.loadedSkills)
+	a.mu.Unlock()
+
+	var scoped []string
+	for _, name := range loaded {
+		s, ok := a.Skills.Get(name)
+		if !ok {
+			continue
+		}
+		if s.AllowsTool(names...) {
+			return nil
+		}
+		scoped = append(scoped, fmt.Sprintf("%s: %s", s.Name, strings.Join(s.AllowedTools, ", ")))
+	}
+	if len(scoped) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "\n⛔ 已拒绝调用 %s：不在已加载技能允许的工具范围内\n", toolName)
+	return fmt.Errorf("%w: %s is not in the allowed-tools of the loaded skills (%s)", policy.ErrDenied, toolName, strings.Join(scoped, "; "))
+}
+
+// mcpCheckTimeout bounds connecting to the MCP servers when validating skills
+const mcpCheckTimeout = 30 * time.Second
+
+// RunSkills implements the skills subcommand for the skills in dirs: list
+// them, show one's manifest, validate them, or scaffold a new one. connect
+// returns the MCP servers that validate checks allowed-tools against.
+func RunSkills(name string, args []string, dirs []string, connect func() (*mcp.Manager, error)) error {
+	usage := fmt.Sprintf("Usage: %[1]s skills [list]\n       %[1]s skills show <name>\n"+
+		"       %[1]s skills validate [-offline] [skill directory or name...]\n       %[1]s skills init [-dir directory] <name>", name)
+
+	cmd := "list"
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+	switch cmd {
+	case "list":
+		return listSkills(dirs)
+	case "show":
+		if len(args) != 1 {
+			return fmt.Errorf("skills show needs one skill name or directory\n%s", usage)
+		}
+		return showSkill(dirs, args[0])
+	case "validate":
+		return validateSkills(name, args, dirs, connect)
+	case "init":
+		return initSkill(name, args, dirs)
+	case "help", "-h", "-help", "--help":
+		fmt.Println(usage)
+		return nil
+	}
+	return fmt.Errorf("unknown skills command %q\n%s", cmd, usage)
+}
+
+// listSkills prints the skills found in dirs with their version and location
+func listSkills(dirs []string) error {
+	registry, err := skill.Load(dirs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Some skills could not be loaded:\n%v\n\n", err)
+	}
+	if registry.Len() == 0 {
+		fmt.Printf("No skills found in %s.\n", strings.Join(dirs, ", "))
+		return nil
+	}
+
+	fmt.Printf("Skills (searched %s):\n", strings.Join(dirs, ", "))
+	for _, s := range registry.All() {
+		version := s.Version
+		if version == "" {
+			version = "-"
+		}
+		fmt.Printf("  %-20s %-10s %s\n", s.Name, version, s.Dir)
+		fmt.Printf("  %-20s %s\n", "", strings.Join(strings.Fields(s.Description), " "))
+	}
+	return nil
+}
+
+// showSkill prints the parsed manifest of a skill
+func showSkill(dirs []string, target string) error {
+	s, err := findSkill(dirs, target)
+	if err != nil {
+		return err
+	}
+
+	field := func(label, value string) {
+		if value != "" {
+			fmt.Printf("%-14s %s\n", label+":", value)
+		}
+	}
+	field("Name", s.Name)
+	field("Description", strings.Join(strings.Fields(s.Description), " "))
+	field("Version", s.Version)
+	field("License", s.License)
+	field("Path", s.Path())
+
+	env := make([]string, len(s.Env))
+	missing := s.MissingEnv()
+	for i, name := range s.Env {
+		env[i] = name
+		if slices.Contains(missing, name) {
+			env[i] += " (not set)"
+		}
+	}
+	field("Env", strings.Join(env, ", "))
+
+	allowed := strings.Join(s.AllowedTools, ", ")
+	if allowed == "" {
+		allowed = "any tool"
+	}
+	field("Allowed tools", allowed)
+	field("Scripts", strings.Join(s.Scripts, ", "))
+	field("References", strings.Join(s.References, ", "))
+
+	keys := make([]string, 0, len(s.Metadata))
+	for k := range s.Metadata {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		field("Metadata", fmt.Sprintf("%s=%s", k, s.Metadata[k]))
+	}
+	field("Body", fmt.Sprintf("%d lines", strings.Count(s.Body, "\n")+1))
+	return nil
+}
+
+// findSkill reads the skill in the directory target, or else the discovered skill named target
+func findSkill(dirs []string, target string) (*skill.Skill, error) {
+	if info, err := os.Stat(filepath.Join(target, skill.FileName)); err == nil && !info.IsDir() {
+		return skill.Read(target)
+	}
+	registry, _ := skill.Load(dirs)
+	if s, ok := registry.Get(target); ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("no skill named %q in %s and no %s in that directory", target, strings.Join(dirs, ", "), skill.FileName)
+}
+
+// validateSkills checks the given skills, or every skill in dirs: the
+// frontmatter, the declared scripts and references, that allowed-tools lets
+// the scripts run, and that it names tools the MCP servers offer
+func validateSkills(name string, args []string, dirs []string, connect func() (*mcp.Manager, error)) error {
+	flags := flag.NewFlagSet(name+" skills validate", flag.ContinueOnError)
+	offline := flags.Bool("offline", false, "do not connect to the MCP servers to check allowed-tools")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	targets := flags.Args()
+	if len(targets) == 0 {
+		found, err := skill.Discover(dirs)
+		if err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			fmt.Printf("No skills found in %s.\n", strings.Join(dirs, ", "))
+			return nil
+		}
+		targets = found
+	}
+
+	var tools *knownTools
+	failed := 0
+	for _, target := range targets {
+		s, err := findSkill(dirs, target)
+		if err != nil {
+			fmt.Printf("✗ %s\n", target)
+			printProblems("error", err)
+			failed++
+			continue
+		}
+
+		var errs, warnings []error
+		errs = append(errs, s.CheckFiles())
+		if len(s.Scripts) > 0 && len(s.AllowedTools) > 0 && !s.AllowsTool(bashTool.Function.Name) {
+			errs = append(errs, fmt.Errorf("scripts are declared but allowed-tools does not include %s, so they cannot run once the skill is loaded", bashTool.Function.Name))
+		}
+		if missing := s.MissingEnv(); len(missing) > 0 {
+			warnings = append(warnings, fmt.Errorf("environment variables not set here: %s", strings.Join(missing, ", ")))
+		}
+		if base := filepath.Base(s.Dir); base != s.Name {
+			warnings = append(warnings, fmt.Errorf("directory %s does not match the skill name %s", base, s.Name))
+		}
+
+		if len(s.AllowedTools) > 0 && !*offline {
+			if tools == nil {
+				tools = listKnownTools(connect)
+			}
+			if tools.err != nil {
+				warnings = append(warnings, fmt.Errorf("allowed-tools not checked: %w", tools.err))
+			} else {
+				toolErrs, toolWarnings := tools.check(s.AllowedTools)
+				errs = append(errs, toolErrs...)
+				warnings = append(warnings, toolWarnings...)
+			}
+		}
+
+		err = errors.Join(errs...)
+		mark := "✓"
+		if err != nil {
+			mark = "✗"
+			failed++
+		}
+		fmt.Printf("%s %s  %s\n", mark, s.Name, s.Dir)
+		printProblems("error", err)
+		printProblems("warning", errors.Join(warnings...))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d skills failed validation", failed, len(targets))
+	}
+	return nil
+}
+
+// printProblems prints each line of err under a skill, if err is not nil
+func printProblems(kind string, err error) {
+	if err == nil {
+		return
+	}
+	for _, line := range strings.Split(err.Error(), "\n") {
+		fmt.Printf("    %s: %s\n", kind, line)
+	}
+}
+
+// knownTools are the names a skill's allowed-tools may refer to: the
+// built-in tools and each MCP tool as exposed and as "server/tool"
+type knownTools struct {
+	names []string
+	err   error // Why the MCP servers could not be listed
+}
+
+// listKnownTools connects to the MCP servers and lists their tools
+func listKnownTools(connect func() (*mcp.Manager, error)) *knownTools {
+	known := &knownTools{}
+	for _, tool := range []llm.Tool{bashTool, listResourcesTool, readResourceTool, readToolOutputTool, loadSkillTool} {
+		known.names = append(known.names, tool.Function.Name)
+	}
+
+	manager, err := connect()
+	if err != nil {
+		known.err = err
+		return known
+	}
+	defer manager.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), mcpCheckTimeout)
+	defer cancel()
+	if err := manager.Initialize(ctx); err != nil {
+		known.err = fmt.Errorf("failed to connect to MCP server: %w", err)
+		return known
+	}
+
+	for _, tool := range manager.Tools() {
+		known.names = append(known.names, tool.Name)
+		if server, name, ok := manager.Lookup(tool.Name); ok {
+			known.names = append(known.names, server+"/"+name)
+		}
+	}
+	return known
+}
+
+// check reports allowed-tools names that match no tool as errors and
+// patterns that match none as warnings
+func (k *knownTools) check(allowed []string) (errs, warnings []error) {
+	for _, entry := range allowed {
+		probe := &skill.Skill{AllowedTools: []string{entry}}
+		if probe.AllowsTool(k.names...) {
+			continue
+		}
+		if skill.IsPattern(entry) {
+			warnings = append(warnings, fmt.Errorf("allowed-tools pattern %q matches no tool", entry))
+		} else {
+			errs = append(errs, fmt.Errorf("allowed-tools entry %q is not a built-in tool or a tool of any MCP server", entry))
+		}
+	}
+	return errs, warnings
+}
+
+// initSkill scaffolds a new skill in the first skill directory or -dir
+func initSkill(name string, args []string, dirs []string) error {
+	flags := flag.NewFlagSet(name+" skills init", flag.ContinueOnError)
+	parent := flags.String("dir", dirs[0], "directory to create the skill in")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("skills init needs the name of the new skill")
+	}
+
+	dir, err := skill.Scaffold(*parent, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created %s.\nEdit %s, then check it with: %s skills validate %s\n",
+		dir, filepath.Join(dir, skill.FileName), name, dir)
+	return nil
+}
@@ -0,0 +1,332 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cnb.cool/znb/learn-skills/internal/audit"
+	"cnb.cool/znb/learn-skills/internal/config"
+	"cnb.cool/znb/learn-skills/internal/llm"
+	"cnb.cool/znb/learn-skills/internal/mcp"
+	"cnb.cool/znb/learn-skills/internal/policy"
+	"cnb.cool/znb/learn-skills/internal/skill"
+)
+
+// testSkills are the skills most tests load
+var testSkills = map[string]string{
+	"cnb-skill": "---\nname: cnb-skill\ndescription: CNB operations\nallowed-tools: [cnb/*, list_mcp_resources]\n---\nUse the CNB tools.\n",
+	"release":   "---\nname: release\ndescription: Cut releases\nallowed-tools: [execute_bash]\n---\nRun the release script.\n",
+	"notes":     "---\nname: notes\ndescription: Take notes\n---\nWrite notes.\n",
+}
+
+func TestCompactionKeepsLoadedSkills(t *testing.T) {
+	_, client := newFakeLLM(t, func(req chatRequest) llm.Message {
+		return llm.Message{Content: "The user asked about builds."}
+	})
+	a := newTestAssistant(t, client, testSkills)
+	a.Config.Context = config.ContextConfig{Budget: 1, KeepTurns: 1}
+
+	result, err := a.loadSkill("cnb-skill", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Messages = append(a.Messages,
+		llm.Message{Role: "user", Content: "Show my builds"},
+		llm.Message{Role: "assistant", ToolCalls: []llm.ToolCall{toolCall("c1", "load_skill", `{"name":"cnb-skill"}`)}},
+		llm.Message{Role: "tool", ToolCallID: "c1", Content: result},
+		llm.Message{Role: "assistant", Content: "Build #1 passed."},
+	)
+
+	for turn := 0; turn < 2; turn++ {
+		a.Messages = append(a.Messages, llm.Message{Role: "user", Content: "And now?"})
+		if err := a.compactHistory(context.Background(), nil); err != nil {
+			t.Fatal(err)
+		}
+
+		var carried []string
+		for _, msg := range a.Messages {
+			if msg.Role == "system" && strings.HasPrefix(msg.Content, skillPrefix) {
+				carried = append(carried, msg.Content)
+			}
+		}
+		if len(carried) != 1 || !strings.Contains(carried[0], "Use the CNB tools.") {
+			t.Fatalf("turn %d: expected the cnb-skill instructions carried over once, got %q", turn, carried)
+		}
+		a.Messages = append(a.Messages, llm.Message{Role: "assistant", Content: "Nothing new."})
+	}
+}
+
+func TestCheckSkillTools(t *testing.T) {
+	cases := []struct {
+		name    string
+		loaded  []string
+		tool    string
+		allowed bool
+	}{
+		{"nothing loaded", nil, "execute_bash", true},
+		{"allowed by a scoped skill", []string{"cnb-skill"}, "list_mcp_resources", true},
+		{"allowed by another scoped skill", []string{"cnb-skill", "release"}, "execute_bash", true},
+		{"outside every scoped skill", []string{"cnb-skill", "release"}, "read_mcp_resource", false},
+		{"an unscoped skill allows all", []string{"cnb-skill", "notes"}, "execute_bash", true},
+		{"load_skill always allowed", []string{"cnb-skill"}, "load_skill", true},
+		{"read_tool_output always allowed", []string{"release"}, "read_tool_output", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := newTestAssistant(t, nil, testSkills)
+			a.loadedSkills = tc.loaded
+
+			err := a.checkSkillTools(tc.tool)
+			if tc.allowed && err != nil {
+				t.Fatalf("Expected %s allowed, got %v", tc.tool, err)
+			}
+			if !tc.allowed && !errors.Is(err, policy.ErrDenied) {
+				t.Fatalf("Expected %s denied, got %v", tc.tool, err)
+			}
+		})
+	}
+}
+
+func TestSkillToolsDenialAudited(t *testing.T) {
+	a := newTestAssistant(t, nil, testSkills)
+	pol, err := policy.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	a.Policy, a.Audit = pol, audit.Open(path)
+	a.loadedSkills = []string{"cnb-skill"}
+
+	if _, err := a.ExecuteTool(context.Background(), "execute_bash", `{"command":"rm -rf dist"}`); !errors.Is(err, policy.ErrDenied) {
+		t.Fatalf("Expected the call denied, got %v", err)
+	}
+
+	entries, err := audit.Query(path, audit.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected one audit entry, got %d", len(entries))
+	}
+	e := entries[0]
+	want := pol.Classify("execute_bash", nil).String()
+	if e.Tool != "execute_bash" || e.Approval != string(policy.Refused) || e.Status != "denied" || e.Class != want {
+		t.Errorf("Expected a refused, denied %s entry, got %+v", want, e)
+	}
+}
+
+func TestValidateFlagsScriptsThatCannotRun(t *testing.T) {
+	dir := t.TempDir()
+	skillDir := filepath.Join(dir, "report")
+	if err := os.MkdirAll(filepath.Join(skillDir, "scripts"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(skillDir, "scripts", "report.sh"), []byte("#!/bin/sh\necho ok\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		allowed string
+		valid   bool
+	}{
+		{"[list_mcp_resources]", false},
+		{"[list_mcp_resources, execute_bash]", true},
+	} {
+		manifest := "---\nname: report\ndescription: Build reports\nallowed-tools: " + tc.allowed + "\nscripts: [scripts/report.sh]\n---\nRun the script.\n"
+		if err := os.WriteFile(filepath.Join(skillDir, skill.FileName), []byte(manifest), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		err := RunSkills("learn-skills", []string{"validate", "-offline", skillDir}, []string{dir}, nil)
+		if tc.valid && err != nil {
+			t.Errorf("allowed-tools %s: expected valid, got %v", tc.allowed, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("allowed-tools %s: expected the script to be flagged", tc.allowed)
+		}
+	}
+}
+
+// captureStdout returns what fn prints to standard output
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- string(data)
+	}()
+	fn()
+	w.Close()
+	return <-out
+}
+
+// writeSkill creates dir/name with the given SKILL.md and extra files as
+// path to content; files under scripts/ are made executable
+func writeSkill(t *testing.T, dir, name, manifest string, files map[string]string) string {
+	t.Helper()
+
+	skillDir := filepath.Join(dir, name)
+	all := map[string]string{skill.FileName: manifest}
+	for path, content := range files {
+		all[path] = content
+	}
+	for path, content := range all {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(skillDir, path)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		mode := os.FileMode(0o644)
+		if strings.HasPrefix(path, "scripts/") {
+			mode = 0o755
+		}
+		if err := os.WriteFile(filepath.Join(skillDir, path), []byte(content), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return skillDir
+}
+
+func TestRunSkillsList(t *testing.T) {
+	dir := t.TempDir()
+	for name, manifest := range testSkills {
+		writeSkill(t, dir, name, manifest, nil)
+	}
+	writeSkill(t, dir, "broken", "no frontmatter\n", nil)
+
+	var err error
+	out := captureStdout(t, func() { err = RunSkills("learn-skills", nil, []string{dir}, nil) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"cnb-skill", "CNB operations", "release", "Cut releases", "notes", "Take notes"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the list, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "broken") {
+		t.Errorf("Expected the broken skill left out of the list, got:\n%s", out)
+	}
+
+	out = captureStdout(t, func() { err = RunSkills("learn-skills", []string{"list"}, []string{t.TempDir()}, nil) })
+	if err != nil || !strings.Contains(out, "No skills found") {
+		t.Errorf("Expected no skills found, got %v:\n%s", err, out)
+	}
+}
+
+func TestRunSkillsShow(t *testing.T) {
+	dir := t.TempDir()
+	manifest := "---\nname: cnb-skill\ndescription: CNB operations\nversion: 1.2.0\nallowed-tools: [cnb/*, execute_bash]\n" +
+		"scripts: [scripts/cnb-mcp.py]\nreferences: [docs/api.md]\n---\nUse the CNB tools.\n"
+	skillDir := writeSkill(t, dir, "cnb-skill", manifest, map[string]string{"scripts/cnb-mcp.py": "", "docs/api.md": ""})
+
+	for _, target := range []string{"cnb-skill", skillDir} {
+		var err error
+		out := captureStdout(t, func() { err = RunSkills("learn-skills", []string{"show", target}, []string{dir}, nil) })
+		if err != nil {
+			t.Fatalf("show %s: %v", target, err)
+		}
+		for _, want := range []string{"Name:          cnb-skill", "Version:       1.2.0", "Allowed tools: cnb/*, execute_bash",
+			"Scripts:       scripts/cnb-mcp.py", "References:    docs/api.md"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("show %s: expected %q, got:\n%s", target, want, out)
+			}
+		}
+	}
+
+	if err := RunSkills("learn-skills", []string{"show", "deploy"}, []string{dir}, nil); err == nil {
+		t.Error("Expected an error for an unknown skill")
+	}
+	if err := RunSkills("learn-skills", []string{"show"}, []string{dir}, nil); err == nil {
+		t.Error("Expected an error without a skill name")
+	}
+}
+
+func TestRunSkillsValidate(t *testing.T) {
+	// The fake MCP server "fake" offers one tool, "work"
+	connect := func() (*mcp.Manager, error) {
+		manager := mcp.NewManager()
+		manager.Add("fake", "", mcp.NewClientWithTransport("fake", &fakeTools{}))
+		return manager, nil
+	}
+	unreachable := func() (*mcp.Manager, error) {
+		return nil, errors.New("no MCP servers configured")
+	}
+
+	cases := []struct {
+		name     string
+		manifest string
+		files    map[string]string
+		connect  func() (*mcp.Manager, error)
+		valid    bool
+		output   string
+	}{
+		{name: "MCP tool by name", manifest: "allowed-tools: [work]\n", valid: true},
+		{name: "MCP tool by server", manifest: "allowed-tools: [fake/work, execute_bash]\n", valid: true},
+		{name: "server pattern", manifest: "allowed-tools: [fake/*]\n", valid: true},
+		{name: "unknown tool", manifest: "allowed-tools: [deploy]\n", output: `entry "deploy" is not a built-in tool`},
+		{name: "pattern matching nothing", manifest: "allowed-tools: [gh/*]\n", valid: true, output: `warning: allowed-tools pattern "gh/*" matches no tool`},
+		{name: "servers unreachable", manifest: "allowed-tools: [deploy]\n", connect: unreachable, valid: true, output: "allowed-tools not checked"},
+		{name: "missing script", manifest: "scripts: [scripts/report.sh]\n", output: "script scripts/report.sh"},
+		{name: "missing reference", manifest: "references: [docs/api.md]\n", output: "reference docs/api.md"},
+		{
+			name:     "script and reference present",
+			manifest: "scripts: [scripts/report.sh]\nreferences: [docs/api.md]\n",
+			files:    map[string]string{"scripts/report.sh": "#!/bin/sh\n", "docs/api.md": "# API\n"},
+			valid:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			manifest := "---\nname: report\ndescription: Build reports\n" + tc.manifest + "---\nBuild the report.\n"
+			skillDir := writeSkill(t, dir, "report", manifest, tc.files)
+			if tc.connect == nil {
+				tc.connect = connect
+			}
+
+			var err error
+			out := captureStdout(t, func() {
+				err = RunSkills("learn-skills", []string{"validate", skillDir}, []string{dir}, tc.connect)
+			})
+			if tc.valid && err != nil {
+				t.Errorf("Expected valid, got %v:\n%s", err, out)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("Expected validation to fail:\n%s", out)
+			}
+			if !strings.Contains(out, tc.output) {
+				t.Errorf("Expected %q in the output, got:\n%s", tc.output, out)
+			}
+		})
+	}
+}
+
+func TestRunSkillsValidateAll(t *testing.T) {
+	dir := t.TempDir()
+	writeSkill(t, dir, "notes", testSkills["notes"], nil)
+	writeSkill(t, dir, "report", "---\nname: report\ndescription: Build reports\nreferences: [docs/api.md]\n---\nBuild the report.\n", nil)
+
+	var err error
+	out := captureStdout(t, func() { err = RunSkills("learn-skills", []string{"validate", "-offline"}, []string{dir}, nil) })
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 skills failed validation") {
+		t.Errorf("Expected one of two skills to fail, got %v", err)
+	}
+	if !strings.Contains(out, "✓ notes") || !strings.Contains(out, "✗ report") {
+		t.Errorf("Expected notes valid and report flagged, got:\n%s", out)
+	}
+}
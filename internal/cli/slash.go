@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cnb.cool/znb/learn-skills/internal/mcp"
+)
+
+// handleSlashCommand runs an interactive slash command.
+// It returns the text to send to the model when the command expands an MCP
+// prompt, or "" when the command was fully handled locally.
+func handleSlashCommand(ctx context.Context, assistant *Assistant, input string) (string, error) {
+	tokens := splitArgs(strings.TrimPrefix(input, "/"))
+	if len(tokens) == 0 {
+		return "", fmt.Errorf("empty command, try /prompts")
+	}
+	name, rest := tokens[0], tokens[1:]
+
+	switch name {
+	case "prompts":
+		return "", printPrompts(ctx, assistant)
+	case "resources":
+		server := ""
+		if len(rest) > 0 {
+			server = rest[0]
+		}
+		return "", printResources(ctx, assistant, server)
+	case "sessions":
+		if assistant.Sessions == nil {
+			return "", fmt.Errorf("sessions are disabled")
+		}
+		return "", PrintSessions(assistant.Sessions)
+	case "resume":
+		if len(rest) != 1 {
+			return "", fmt.Errorf("usage: /resume <session-id|last>")
+		}
+		return "", resumeSession(assistant, rest[0])
+	}
+
+	return assistant.expandPrompt(ctx, name, rest)
+}
+
+// resumeSession switches the conversation to a stored session and shows where it left off
+func resumeSession(assistant *Assistant, id string) error {
+	if err := assistant.ResumeSession(id); err != nil {
+		return err
+	}
+
+	s := assistant.Session
+	fmt.Printf("Resumed session %s (%d messages): %s\n", s.ID, len(s.Messages), s.Title)
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		if msg := s.Messages[i]; msg.Role == "assistant" && msg.Content != "" {
+			fmt.Printf("\nLast reply:\n%s\n", assistant.redact(msg.Content))
+			break
+		}
+	}
+	return nil
+}
+
+// printPrompts lists the prompts offered by the MCP servers
+func printPrompts(ctx context.Context, assistant *Assistant) error {
+	prompts, err := assistant.MCP.ListPrompts(ctx)
+	if err != nil {
+		return err
+	}
+	if len(prompts) == 0 {
+		fmt.Println("No MCP prompts available.")
+		return nil
+	}
+
+	fmt.Println("MCP prompts (invoke with /<name> key=value ...):")
+	for _, p := range prompts {
+		fmt.Printf("  /%s:%s", p.Server, p.Name)
+		if p.Description != "" {
+			fmt.Printf("  - %s", p.Description)
+		}
+		fmt.Println()
+		for _, arg := range p.Arguments {
+			required := ""
+			if arg.Required {
+				required = " (required)"
+			}
+			fmt.Printf("      %s%s  %s\n", arg.Name, required, arg.Description)
+		}
+	}
+	return nil
+}
+
+// printResources lists the resources offered by the MCP servers
+func printResources(ctx context.Context, assistant *Assistant, server string) error {
+	resources, err := assistant.MCP.ListResources(ctx, server)
+	if err != nil {
+		return err
+	}
+	if len(resources) == 0 {
+		fmt.Println("No MCP resources available.")
+		return nil
+	}
+
+	fmt.Println("MCP resources:")
+	for _, r := range resources {
+		fmt.Printf("  [%s] %s  %s", r.Server, r.URI, r.Name)
+		if r.Description != "" {
+			fmt.Printf("  - %s", r.Description)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// expandPrompt resolves /<prompt> or /<server>:<prompt> and returns the
+// expanded prompt text to send as the user message
+func (a *Assistant) expandPrompt(ctx context.Context, name string, rawArgs []string) (string, error) {
+	server, promptName, qualified := strings.Cut(name, ":")
+	if !qualified {
+		server, promptName = "", name
+	}
+
+	prompts, err := a.MCP.ListPrompts(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var matches []mcp.ServerPrompt
+	for _, p := range prompts {
+		if p.Name == promptName && (server == "" || p.Server == server) {
+			matches = append(matches, p)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("unknown command /%s (type /prompts to list MCP prompts)", name)
+	case 1:
+	default:
+		return "", fmt.Errorf("prompt %s is offered by several servers, use /<server>:%s", promptName, promptName)
+	}
+	prompt := matches[0]
+
+	args := make(map[string]string, len(rawArgs))
+	for _, raw := range rawArgs {
+		key, value, ok := strings.Cut(raw, "=")
+		if !ok {
+			return "", fmt.Errorf("argument %q must be key=value", raw)
+		}
+		args[key] = value
+	}
+	for _, arg := range prompt.Arguments {
+		if _, ok := args[arg.Name]; arg.Required && !ok {
+			return "", fmt.Errorf("prompt %s requires argument %s", prompt.Name, arg.Name)
+		}
+	}
+
+	result, err := a.MCP.GetPrompt(ctx, prompt.Server, prompt.Name, args)
+	if err != nil {
+		return "", err
+	}
+
+	return promptText(result), nil
+}
+
+// promptText flattens prompt messages into a single user message.
+// Non-user messages are labelled with their role so the model keeps the structure.
+func promptText(result *mcp.GetPromptResult) string {
+	var parts []string
+	for _, msg := range result.Messages {
+		text := (&mcp.CallToolResult{Content: []mcp.Content{msg.Content}}).Text()
+		if msg.Role != "user" {
+			text = fmt.Sprintf("[%s]\n%s", msg.Role, text)
+		}
+		parts = append(parts, text)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// splitArgs splits a command line on spaces, keeping quoted sections together
+// and removing the quotes
+func splitArgs(s string) []string {
+	var tokens []string
+	var current strings.Builder
+	inToken := false
+	quote := rune(0)
+
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inToken = true
+		case r == ' ' || r == '\t':
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens
+}
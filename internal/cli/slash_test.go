@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cnb.cool/znb/learn-skills/internal/mcp"
+)
+
+func TestSplitArgs(t *testing.T) {
+	cases := []struct {
+		input string
+		want  []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"review pr=42", []string{"review", "pr=42"}},
+		{"review  pr=42\tfocus=tests ", []string{"review", "pr=42", "focus=tests"}},
+		{`review focus="error handling"`, []string{"review", "focus=error handling"}},
+		{`review focus='say "hi"'`, []string{"review", `focus=say "hi"`}},
+		{`review title=""`, []string{"review", "title="}},
+		{`review ""`, []string{"review", ""}},
+		{`review focus="unterminated quote`, []string{"review", "focus=unterminated quote"}},
+	}
+
+	for _, tc := range cases {
+		if got := splitArgs(tc.input); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("splitArgs(%q) = %q, want %q", tc.input, got, tc.want)
+		}
+	}
+}
+
+// fakePrompts is an MCP transport offering the prompts given as name to arguments.
+// prompts/get echoes the prompt name and its arguments.
+type fakePrompts map[string][]mcp.PromptArgument
+
+func (f fakePrompts) Connect(ctx context.Context) error                     { return nil }
+func (f fakePrompts) Notify(ctx context.Context, n *mcp.Notification) error { return nil }
+func (f fakePrompts) Close() error                                          { return nil }
+
+func (f fakePrompts) Send(ctx context.Context, req *mcp.Request, notify mcp.NotifyFunc) (*mcp.Response, error) {
+	var result interface{}
+	switch req.Method {
+	case "initialize":
+		result = map[string]interface{}{
+			"protocolVersion": mcp.ProtocolVersion,
+			"capabilities":    map[string]interface{}{"prompts": map[string]interface{}{}},
+			"serverInfo":      map[string]interface{}{"name": "fake", "version": "1.0"},
+		}
+	case "tools/list":
+		result = map[string]interface{}{"tools": []interface{}{}}
+	case "prompts/list":
+		var prompts []mcp.Prompt
+		for name, args := range f {
+			prompts = append(prompts, mcp.Prompt{Name: name, Arguments: args})
+		}
+		result = map[string]interface{}{"prompts": prompts}
+	case "prompts/get":
+		params := req.Params.(map[string]interface{})
+		text := fmt.Sprintf("%s %v", params["name"], params["arguments"])
+		result = map[string]interface{}{
+			"messages": []map[string]interface{}{
+				{"role": "assistant", "content": map[string]interface{}{"type": "text", "text": "Reviewing."}},
+				{"role": "user", "content": map[string]interface{}{"type": "text", "text": text}},
+			},
+		}
+	}
+
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.Response{JSONRPC: "2.0", ID: json.RawMessage(fmt.Sprint(req.ID)), Result: raw}, nil
+}
+
+func TestExpandPrompt(t *testing.T) {
+	a := newTestAssistant(t, nil, nil)
+	review := []mcp.PromptArgument{{Name: "pr", Required: true}, {Name: "focus"}}
+	a.MCP.Add("cnb", "", mcp.NewClientWithTransport("cnb", fakePrompts{"review": review, "triage": nil}))
+	a.MCP.Add("gh", "", mcp.NewClientWithTransport("gh", fakePrompts{"triage": nil}))
+	if err := a.MCP.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		command string
+		want    string
+		wantErr string
+	}{
+		{command: `/review pr=42 focus="error handling"`, want: "[assistant]\nReviewing.\n\nreview map[focus:error handling pr:42]"},
+		{command: "/cnb:review pr=42", want: "[assistant]\nReviewing.\n\nreview map[pr:42]"},
+		{command: "/gh:triage", want: "[assistant]\nReviewing.\n\ntriage <nil>"},
+		{command: "/review focus=tests", wantErr: "requires argument pr"},
+		{command: "/review 42", wantErr: "must be key=value"},
+		{command: "/triage", wantErr: "offered by several servers"},
+		{command: "/gh:review pr=42", wantErr: "unknown command"},
+		{command: "/deploy", wantErr: "unknown command"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.command, func(t *testing.T) {
+			got, err := handleSlashCommand(context.Background(), a, tc.command)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Expected an error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("Expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -1,28 +1,193 @@
 package cli
 
 import (
+	"fmt"
+
 	"cnb.cool/znb/learn-skills/internal/llm"
+	"cnb.cool/znb/learn-skills/internal/mcp"
 )
 
-// GetCNBTools returns the CNB tool definitions for the LLM
-func GetCNBTools() []llm.Tool {
-	return []llm.Tool{
-		{
-			Type: "function",
-			Function: llm.Function{
-				Name:        "execute_bash",
-				Description: "Execute a bash command and return the output. Use this to call the CNB MCP Python script (skills/scripts/cnb-mcp.py).",
-				Parameters: map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"command": map[string]interface{}{
-							"type":        "string",
-							"description": "The bash command to execute. For CNB operations, use: python3 skills/scripts/cnb-mcp.py <operation> [args]",
-						},
+// bashTool is the built-in tool for running local shell commands
+var bashTool = llm.Tool{
+	Type: "function",
+	Function: llm.Function{
+		Name:        "execute_bash",
+		Description: "Execute a bash command on the local machine and return the output. CNB operations are exposed as dedicated tools; do not use this tool to reach CNB.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"command": map[string]interface{}{
+					"type":        "string",
+					"description": "The bash command to execute",
+				},
+			},
+			"required": []string{"command"},
+		},
+	},
+}
+
+// Built-in tools for MCP resources, offered when any server has resources
+var (
+	listResourcesTool = llm.Tool{
+		Type: "function",
+		Function: llm.Function{
+			Name:        "list_mcp_resources",
+			Description: "List the resources (documents, files, data) offered by the connected MCP servers.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"server": map[string]interface{}{
+						"type":        "string",
+						"description": "Only list resources of this server, e.g. \"cnb\". Omit to list all servers.",
+					},
+				},
+			},
+		},
+	}
+
+	readResourceTool = llm.Tool{
+		Type: "function",
+		Function: llm.Function{
+			Name:        "read_mcp_resource",
+			Description: "Read the contents of an MCP resource returned by list_mcp_resources.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"server": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the server that offers the resource",
 					},
-					"required": []string{"command"},
+					"uri": map[string]interface{}{
+						"type":        "string",
+						"description": "URI of the resource",
+					},
+				},
+				"required": []string{"server", "uri"},
+			},
+		},
+	}
+)
+
+// readToolOutputTool pages through or searches a tool result that was too large to return in full
+var readToolOutputTool = llm.Tool{
+	Type: "function",
+	Function: llm.Function{
+		Name:        "read_tool_output",
+		Description: "Read part of a tool output that was too large to return in full and was stored as an artifact. Give a line range to page through it, or a pattern to list the matching lines.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"id": map[string]interface{}{
+					"type":        "string",
+					"description": "Artifact ID given in the truncated tool result",
+				},
+				"offset": map[string]interface{}{
+					"type":        "integer",
+					"description": "1-based line number to start reading from, default 1",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "Number of lines to read, default 200",
+				},
+				"pattern": map[string]interface{}{
+					"type":        "string",
+					"description": "Regular expression (Go syntax); when set, return the matching lines with their line numbers instead of a range",
+				},
+			},
+			"required": []string{"id"},
+		},
+	},
+}
+
+// loadSkillTool pulls the instructions of a skill listed in the system prompt into the conversation
+var loadSkillTool = llm.Tool{
+	Type: "function",
+	Function: llm.Function{
+		Name:        "load_skill",
+		Description: "Load the instructions of one of the available skills listed in the system prompt. Call it before working on a task the skill covers. With reference, read one of the skill's reference documents instead.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"name": map[string]interface{}{
+					"type":        "string",
+					"description": "Name of the skill, e.g. \"cnb-skill\"",
+				},
+				"reference": map[string]interface{}{
+					"type":        "string",
+					"description": "Path of a reference document listed by the skill; omit to load the skill's instructions",
 				},
 			},
+			"required": []string{"name"},
 		},
+	},
+}
+
+// GetCNBTools returns the tool definitions for the LLM: one function per MCP tool
+// discovered during Initialize (CNB and configured servers), plus the built-in tools.
+// Tools the policy would refuse outright, e.g. writes in read-only mode, are left out.
+func (a *Assistant) GetCNBTools() []llm.Tool {
+	mcpTools := a.MCP.Tools()
+	tools := make([]llm.Tool, 0, len(mcpTools)+5)
+	for _, tool := range mcpTools {
+		if !a.toolAllowed(tool.Name) {
+			continue
+		}
+		// Reported by Initialize; the provider would reject the whole request
+		if llmTool, err := mcpToolToLLM(tool); err == nil {
+			tools = append(tools, llmTool)
+		}
+	}
+	if a.Skills != nil && a.Skills.Len() > 0 {
+		tools = append(tools, loadSkillTool)
+	}
+	if a.MCP.SupportsResources() {
+		tools = append(tools, listResourcesTool, readResourceTool)
 	}
+	if a.Artifacts != nil && a.Config.ToolOutput.MaxBytes > 0 {
+		tools = append(tools, readToolOutputTool)
+	}
+	if a.toolAllowed(bashTool.Function.Name) {
+		tools = append(tools, bashTool)
+	}
+	return tools
+}
+
+// toolAllowed reports whether the policy lets a tool run at all
+func (a *Assistant) toolAllowed(name string) bool {
+	if a.Policy == nil {
+		return true
+	}
+	return a.Policy.Allows(a.Policy.Classify(name, a.MCP.Annotations(name)))
+}
+
+// mcpToolToLLM maps an MCP tool and its inputSchema to an LLM function
+// definition, or fails when the inputSchema is not a valid JSON Schema
+func mcpToolToLLM(tool mcp.Tool) (llm.Tool, error) {
+	params := make(map[string]interface{}, len(tool.InputSchema)+2)
+	for k, v := range tool.InputSchema {
+		// The meta-schema reference is not accepted by every provider
+		if k == "$schema" {
+			continue
+		}
+		params[k] = v
+	}
+	if _, ok := params["type"]; !ok {
+		params["type"] = "object"
+	}
+	if _, ok := params["properties"]; !ok {
+		params["properties"] = map[string]interface{}{}
+	}
+
+	if err := llm.ValidateParameters(params); err != nil {
+		return llm.Tool{}, fmt.Errorf("MCP tool %s: %w", tool.Name, err)
+	}
+
+	return llm.Tool{
+		Type: "function",
+		Function: llm.Function{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  params,
+		},
+	}, nil
 }
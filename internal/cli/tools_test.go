@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"cnb.cool/znb/learn-skills/internal/mcp"
+)
+
+func TestMCPToolToLLM(t *testing.T) {
+	cases := []struct {
+		name        string
+		inputSchema string
+		want        string
+		wantErr     bool
+	}{
+		{
+			name:        "meta-schema dropped",
+			inputSchema: `{"$schema":"http://json-schema.org/draft-07/schema#","type":"object","properties":{"repo":{"type":"string"}},"required":["repo"]}`,
+			want:        `{"type":"object","properties":{"repo":{"type":"string"}},"required":["repo"]}`,
+		},
+		{
+			name:        "nested objects kept",
+			inputSchema: `{"type":"object","properties":{"filter":{"type":"object","properties":{"labels":{"type":"array","items":{"type":"string"}}}}}}`,
+			want:        `{"type":"object","properties":{"filter":{"type":"object","properties":{"labels":{"type":"array","items":{"type":"string"}}}}}}`,
+		},
+		{
+			name:        "enums kept",
+			inputSchema: `{"type":"object","properties":{"state":{"type":"string","enum":["open","closed"]}}}`,
+			want:        `{"type":"object","properties":{"state":{"type":"string","enum":["open","closed"]}}}`,
+		},
+		{
+			name:        "missing properties",
+			inputSchema: `{"type":"object"}`,
+			want:        `{"type":"object","properties":{}}`,
+		},
+		{
+			name:        "no inputSchema",
+			inputSchema: `null`,
+			want:        `{"type":"object","properties":{}}`,
+		},
+		{
+			name:        "undecodable schema",
+			inputSchema: `{"type":"object","properties":["repo"]}`,
+			wantErr:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tool := mcp.Tool{Name: "list_issues", Description: "List issues"}
+			if err := json.Unmarshal([]byte(tc.inputSchema), &tool.InputSchema); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := mcpToolToLLM(tool)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("Expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var want map[string]interface{}
+			if err := json.Unmarshal([]byte(tc.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if got.Function.Name != tool.Name || got.Function.Description != tool.Description {
+				t.Errorf("Expected name and description carried over, got %+v", got.Function)
+			}
+			if !reflect.DeepEqual(got.Function.Parameters, want) {
+				t.Errorf("Expected parameters %v, got %v", want, got.Function.Parameters)
+			}
+		})
+	}
+}
@@ -1,17 +1,29 @@
 package cli
 
 import (
+	"strings"
+	"sync"
 	"time"
 
+	"cnb.cool/znb/learn-skills/internal/artifact"
+	"cnb.cool/znb/learn-skills/internal/audit"
+	"cnb.cool/znb/learn-skills/internal/config"
 	"cnb.cool/znb/learn-skills/internal/llm"
+	"cnb.cool/znb/learn-skills/internal/mcp"
+	"cnb.cool/znb/learn-skills/internal/policy"
+	"cnb.cool/znb/learn-skills/internal/redact"
+	"cnb.cool/znb/learn-skills/internal/sandbox"
+	"cnb.cool/znb/learn-skills/internal/session"
+	"cnb.cool/znb/learn-skills/internal/skill"
 )
 
 // MCPToolInfo stores information about an MCP tool call
 type MCPToolInfo struct {
-	ToolName  string                 // Tool name, e.g. "list_organizations"
-	Arguments map[string]interface{} // Parameter key-value pairs
-	StartTime time.Time              // Start time
-	EndTime   time.Time              // End time
+	Server    string                 `json:"server"`              // Name of the MCP server that served the call, e.g. "cnb"
+	ToolName  string                 `json:"tool_name"`           // Tool name on that server, e.g. "list_organizations"
+	Arguments map[string]interface{} `json:"arguments,omitempty"` // Parameter key-value pairs
+	StartTime time.Time              `json:"start_time"`          // Start time
+	EndTime   time.Time              `json:"end_time"`            // End time
 }
 
 // Duration returns the execution duration
@@ -21,17 +33,90 @@ func (m *MCPToolInfo) Duration() time.Duration {
 
 // Assistant holds the core components
 type Assistant struct {
+	Config               *config.Config
 	LLMClient            *llm.Client
-	Skill                string
+	MCP                  *mcp.Manager
+	Skills               *skill.Registry // Skills listed in the system prompt and loaded on demand
 	Messages             []llm.Message
-	pendingMCPCallEnding []MCPToolInfo // Store MCP call info to print after LLM response
+	Sessions             *session.Store   // Where conversations are persisted, nil to disable
+	Session              *session.Session // The conversation being recorded
+	Artifacts            *artifact.Store  // Where oversized tool outputs are stored, nil to always truncate
+	Policy               *policy.Policy   // Classifies tool calls, nil to run every call unasked
+	Approve              policy.Approver  // Asks before write calls; nil denies them
+	Sandbox              *sandbox.Sandbox // Confines execute_bash commands
+	Redactor             *redact.Redactor // Masks secrets in messages and terminal output
+	Audit                *audit.Log       // Records every tool call, nil to disable
+	pendingMCPCallEnding []MCPToolInfo    // Store MCP call info to print after LLM response
+	turnStart            int              // Length of Messages before the current turn, kept in sync by compaction
+	loadedSkills         []string         // Skills loaded with load_skill in this session, in load order
+	turnSkills           int              // Length of loadedSkills before the current turn
+	mu                   sync.Mutex       // Guards pendingMCPCallEnding and loadedSkills while tool calls run concurrently
+	approvalMu           sync.Mutex       // Serializes approval prompts of concurrent tool calls
 }
 
-// NewAssistant creates a new assistant instance
-func NewAssistant(llmClient *llm.Client, skill string) *Assistant {
-	return &Assistant{
+// NewAssistant creates a new assistant instance.
+// Each turn is saved to sessions when it is not nil; oversized tool outputs go to artifacts.
+// Write and destructive tool calls are checked against pol; bash commands run in box.
+// Every tool call is appended to auditLog when it is not nil.
+func NewAssistant(cfg *config.Config, llmClient *llm.Client, mcpManager *mcp.Manager, skills *skill.Registry, sessions *session.Store, artifacts *artifact.Store, pol *policy.Policy, box *sandbox.Sandbox, auditLog *audit.Log) *Assistant {
+	a := &Assistant{
+		Config:    cfg,
 		LLMClient: llmClient,
-		Skill:     skill,
+		MCP:       mcpManager,
+		Skills:    skills,
 		Messages:  []llm.Message{},
+		Sessions:  sessions,
+		Artifacts: artifacts,
+		Policy:    pol,
+		Sandbox:   box,
+		Redactor:  newRedactor(cfg),
+		Audit:     auditLog,
 	}
+	if sessions != nil {
+		a.Session = sessions.New()
+	}
+	return a
+}
+
+// newRedactor masks the configured credentials on top of the well-known secret formats.
+// Of the MCP server headers only credential headers count, so values such as
+// a Content-Type or an organization name stay readable.
+func newRedactor(cfg *config.Config) *redact.Redactor {
+	secrets := []string{cfg.LLM.APIKey, cfg.CNB.Token}
+	for _, s := range cfg.MCPServers {
+		secrets = append(secrets, s.Token)
+		for name, v := range s.Headers {
+			if !isCredentialHeader(name) {
+				continue
+			}
+			// The credential also appears without its scheme, e.g. "Bearer <token>"
+			secrets = append(secrets, v)
+			if fields := strings.Fields(v); len(fields) > 1 {
+				secrets = append(secrets, fields[len(fields)-1])
+			}
+		}
+	}
+	return redact.New(secrets...)
+}
+
+// isCredentialHeader reports whether an HTTP header carries a credential:
+// Authorization, Proxy-Authorization, Cookie, or a name ending in -Token,
+// -Key or -Secret such as X-Api-Key
+func isCredentialHeader(name string) bool {
+	name = strings.ToLower(name)
+	switch name {
+	case "authorization", "proxy-authorization", "cookie":
+		return true
+	}
+	for _, suffix := range []string{"-token", "-key", "-secret"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact masks secrets in s before it is stored, sent to the LLM or printed
+func (a *Assistant) redact(s string) string {
+	return a.Redactor.String(s)
 }
@@ -2,13 +2,30 @@ package config
 
 import (
 	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	LLM LLMConfig `mapstructure:"llm"`
-	CNB CNBConfig `mapstructure:"cnb"`
+	LLM        LLMConfig         `mapstructure:"llm"`
+	CNB        CNBConfig         `mapstructure:"cnb"`
+	MCPServers []MCPServerConfig `mapstructure:"mcp_servers"`
+	Timeouts   TimeoutsConfig    `mapstructure:"timeouts"`
+	Context    ContextConfig     `mapstructure:"context"`
+	ToolOutput ToolOutputConfig  `mapstructure:"tool_output"`
+	Approval   ApprovalConfig    `mapstructure:"approval"`
+	// ReadOnly hides and refuses every tool that can change state
+	ReadOnly bool          `mapstructure:"read_only"`
+	Sandbox  SandboxConfig `mapstructure:"sandbox"`
+	Audit    AuditConfig   `mapstructure:"audit"`
+	Tools    ToolsConfig   `mapstructure:"tools"`
+	Skills   SkillsConfig  `mapstructure:"skills"`
 }
 
 // LLMConfig holds LLM client configuration
@@ -23,12 +40,286 @@ type CNBConfig struct {
 	Token   string `mapstructure:"token"`
 	MCPURL  string `mapstructure:"mcp_url"`
 	APIBase string `mapstructure:"api_base"`
+	// Transport is "auto", "streamable-http" or "sse"
+	Transport string `mapstructure:"transport"`
+}
+
+// TimeoutsConfig bounds how long the assistant waits. Values are Go durations
+// such as "90s" or "5m"; zero disables the limit.
+type TimeoutsConfig struct {
+	LLM     time.Duration `mapstructure:"llm"`     // A single LLM request, including streaming
+	Tool    time.Duration `mapstructure:"tool"`    // A single tool call (MCP tool or bash command)
+	Turn    time.Duration `mapstructure:"turn"`    // A whole user turn with all its LLM and tool calls
+	Connect time.Duration `mapstructure:"connect"` // Connecting to the MCP servers at startup
+}
+
+// ContextConfig controls when older turns are summarized to keep the
+// conversation within the model's context window
+type ContextConfig struct {
+	// Budget is the number of prompt tokens the history and tools may use;
+	// 0 picks a default from the model name
+	Budget int `mapstructure:"budget"`
+	// Models overrides Budget per model name (matched case-insensitively)
+	Models map[string]int `mapstructure:"models"`
+	// KeepTurns is the number of most recent user turns never summarized
+	KeepTurns int `mapstructure:"keep_turns"`
+}
+
+// ToolOutputConfig limits how much of a single tool result is sent to the model
+type ToolOutputConfig struct {
+	// MaxBytes is the largest result passed on verbatim; bigger ones are
+	// stored to a file and the model gets an excerpt. 0 disables the limit.
+	MaxBytes int `mapstructure:"max_bytes"`
+	// Dir holds the stored outputs, default ~/.cnb-assistant/artifacts
+	Dir string `mapstructure:"dir"`
 }
 
+// ApprovalConfig controls which tool calls need the user's approval
+type ApprovalConfig struct {
+	// Tools overrides the class of tools by name or glob pattern, e.g.
+	// {"cnb_startbuild": "read", "*_delete_*": "destructive"}.
+	// Keys are case-insensitive; values are read, write or destructive.
+	Tools map[string]string `mapstructure:"tools"`
+}
+
+// ToolsConfig controls how the tool calls of a turn are run
+type ToolsConfig struct {
+	// Concurrency is how many tool calls of one model reply run at once; 1 runs them in order
+	Concurrency int `mapstructure:"concurrency"`
+	// MaxIterations is how many rounds of tool calls one request may take
+	// before the model is asked to answer with what it has
+	MaxIterations int `mapstructure:"max_iterations"`
+	// MaxRepeats is how often the same tool may be called with the same
+	// arguments in one request before the tool loop is stopped
+	MaxRepeats int `mapstructure:"max_repeats"`
+}
+
+// SkillsConfig lists where skills are loaded from
+type SkillsConfig struct {
+	// Dirs are searched in order, each holding one subdirectory per skill;
+	// empty uses the built-in search path
+	Dirs []string `mapstructure:"dirs"`
+}
+
+// AuditConfig controls the audit log of tool calls
+type AuditConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Path    string `mapstructure:"path"` // Default ~/.cnb-assistant/audit.jsonl
+}
+
+// SandboxConfig selects how execute_bash commands are confined
+type SandboxConfig struct {
+	// Profile names the active profile: a built-in one ("default", "strict")
+	// or one defined under Profiles
+	Profile  string                    `mapstructure:"profile"`
+	Profiles map[string]SandboxProfile `mapstructure:"profiles"`
+}
+
+// SandboxProfile confines execute_bash commands. Patterns are Go regular
+// expressions matched against the whole command line.
+type SandboxProfile struct {
+	Allow          []string `mapstructure:"allow"`            // If set, a command must match one of these
+	Deny           []string `mapstructure:"deny"`             // Commands matching any of these are refused
+	Dir            string   `mapstructure:"dir"`              // Working directory, default the current one
+	RestrictPaths  bool     `mapstructure:"restrict_paths"`   // Refuse absolute, ~ and .. paths, keeping commands inside Dir
+	Env            []string `mapstructure:"env"`              // Extra variables passed through; CNB_TOKEN here exports cnb.token
+	CPUSeconds     int      `mapstructure:"cpu_seconds"`      // CPU time limit, 0 for none
+	MemoryMB       int      `mapstructure:"memory_mb"`        // Virtual memory limit, 0 for none
+	MaxOutputBytes int      `mapstructure:"max_output_bytes"` // Output kept from a command, 0 for no limit
+	Isolate        bool     `mapstructure:"isolate"`          // Run in new Linux user, PID, mount, IPC and UTS namespaces
+	NoNetwork      bool     `mapstructure:"no_network"`       // With Isolate, also cut off the network
+}
+
+// defaultDeny refuses commands that can wreck the machine or escalate privileges
+var defaultDeny = []string{
+	`\brm\s+(-[a-zA-Z]*\s+)*-[a-zA-Z]*[rR][a-zA-Z]*\s+(-[a-zA-Z]*\s+)*(/\*?|~/?|\$HOME/?)(\s|$)`,
+	`\b(sudo|su|doas)\b`,
+	`\b(mkfs(\.\w+)?|fdisk|parted|shutdown|reboot|halt|poweroff)\b`,
+	`\bdd\b.*\bof=/dev/`,
+	`:\(\)\s*\{`,
+	`\b(curl|wget)\b[^|]*\|\s*(ba|z)?sh\b`,
+}
+
+// builtinSandboxProfiles are used when Profiles does not define the selected name
+var builtinSandboxProfiles = map[string]SandboxProfile{
+	"default": {
+		Deny:           defaultDeny,
+		Env:            []string{"CNB_TOKEN"},
+		CPUSeconds:     60,
+		MemoryMB:       2048,
+		MaxOutputBytes: 1 << 20,
+	},
+	"strict": {
+		Allow: []string{
+			`^\s*(ls|cat|head|tail|wc|grep|pwd|echo|date|which|file|stat|du|df|sort|uniq|cut|tr|jq)(\s[^;&|<>$` + "`" + `]*)?$`,
+		},
+		Deny:           defaultDeny,
+		RestrictPaths:  true,
+		CPUSeconds:     10,
+		MemoryMB:       512,
+		MaxOutputBytes: 256 << 10,
+		Isolate:        true,
+		NoNetwork:      true,
+	},
+}
+
+// ActiveProfile returns the selected sandbox profile. Profiles defined in the
+// configuration take precedence over built-in ones of the same name.
+func (c SandboxConfig) ActiveProfile() (SandboxProfile, bool) {
+	if p, ok := c.Profiles[c.Profile]; ok {
+		return p, true
+	}
+	p, ok := builtinSandboxProfiles[c.Profile]
+	return p, ok
+}
+
+// defaultBudgets are prompt budgets for well-known models, about three quarters
+// of their context window so the reply and estimation error still fit.
+// Entries match model names by prefix, longest first.
+var defaultBudgets = map[string]int{
+	"gpt-4":         6000,
+	"gpt-4-32k":     24000,
+	"gpt-4-turbo":   96000,
+	"gpt-4o":        96000,
+	"gpt-4.1":       96000,
+	"gpt-3.5-turbo": 12000,
+	"o1":            96000,
+	"o3":            96000,
+	"deepseek":      48000,
+	"qwen":          24000,
+	"glm-4":         96000,
+}
+
+// fallbackBudget is used for models that are neither configured nor known
+const fallbackBudget = 24000
+
+// BudgetFor returns the prompt token budget for model
+func (c ContextConfig) BudgetFor(model string) int {
+	model = strings.ToLower(model)
+	if budget, ok := c.Models[model]; ok && budget > 0 {
+		return budget
+	}
+	if c.Budget > 0 {
+		return c.Budget
+	}
+
+	best, budget := "", fallbackBudget
+	for prefix, b := range defaultBudgets {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best, budget = prefix, b
+		}
+	}
+	return budget
+}
+
+// MCPServerConfig describes an additional MCP server, either launched as a
+// local process (Command) or reached over HTTP (URL)
+type MCPServerConfig struct {
+	Name    string `mapstructure:"name"`    // Unique name shown in call banners
+	Enabled *bool  `mapstructure:"enabled"` // Defaults to true
+	// ToolPrefix is prepended to the server's tool names, default "<name>__"
+	ToolPrefix string `mapstructure:"tool_prefix"`
+
+	// Local process servers
+	Command string   `mapstructure:"command"` // Executable to launch
+	Args    []string `mapstructure:"args"`
+	Env     []string `mapstructure:"env"` // Extra environment, as KEY=VALUE
+	Dir     string   `mapstructure:"dir"` // Working directory
+
+	// Remote servers; ${VAR} references in Token and Headers are expanded from the environment
+	URL       string            `mapstructure:"url"`
+	Transport string            `mapstructure:"transport"` // auto, streamable-http or sse
+	Token     string            `mapstructure:"token"`     // Sent as a Bearer token
+	Headers   map[string]string `mapstructure:"headers"`
+}
+
+// IsEnabled reports whether the server should be connected
+func (s MCPServerConfig) IsEnabled() bool {
+	return s.Enabled == nil || *s.Enabled
+}
+
+// Prefix returns the tool name prefix for the server
+func (s MCPServerConfig) Prefix() string {
+	if s.ToolPrefix != "" {
+		return s.ToolPrefix
+	}
+	return s.Name + "__"
+}
+
+// serverNamePattern and toolPrefixPattern restrict names to characters valid in LLM function names
+var (
+	serverNamePattern = regexp.MustCompile(`^[a-zA-Z0-9-]{1,32}$`)
+	toolPrefixPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+)
+
 // Load reads configuration from file and environment
 func Load() (*Config, error) {
-	v := viper.New()
+	v, err := read()
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("error unmarshaling config: %w", err)
+	}
 
+	// Validate required fields
+	if cfg.LLM.APIKey == "" {
+		return nil, fmt.Errorf("LLM API key is required (set OPENAI_API_KEY or llm.api_key in config)")
+	}
+	if cfg.CNB.Token == "" {
+		return nil, fmt.Errorf("CNB token is required (set CNB_TOKEN or cnb.token in config)")
+	}
+	if err := validateHTTPURL(cfg.CNB.MCPURL); err != nil {
+		return nil, fmt.Errorf("invalid cnb.mcp_url: %w", err)
+	}
+	if err := validateHTTPURL(cfg.CNB.APIBase); err != nil {
+		return nil, fmt.Errorf("invalid cnb.api_base: %w", err)
+	}
+	switch cfg.CNB.Transport {
+	case "auto", "streamable-http", "sse":
+	default:
+		return nil, fmt.Errorf("invalid cnb.transport %q (expected auto, streamable-http or sse)", cfg.CNB.Transport)
+	}
+	if err := validateMCPServers(cfg.MCPServers); err != nil {
+		return nil, err
+	}
+	if cfg.Timeouts.LLM < 0 || cfg.Timeouts.Tool < 0 || cfg.Timeouts.Turn < 0 || cfg.Timeouts.Connect < 0 {
+		return nil, fmt.Errorf("timeouts must not be negative")
+	}
+	if cfg.Context.Budget < 0 || cfg.Context.KeepTurns < 1 {
+		return nil, fmt.Errorf("context.budget must not be negative and context.keep_turns must be at least 1")
+	}
+	if cfg.ToolOutput.MaxBytes < 0 {
+		return nil, fmt.Errorf("tool_output.max_bytes must not be negative")
+	}
+	if cfg.Tools.Concurrency < 1 {
+		return nil, fmt.Errorf("tools.concurrency must be at least 1")
+	}
+	if cfg.Tools.MaxIterations < 1 {
+		return nil, fmt.Errorf("tools.max_iterations must be at least 1")
+	}
+	if cfg.Tools.MaxRepeats < 1 {
+		return nil, fmt.Errorf("tools.max_repeats must be at least 1")
+	}
+	if _, ok := cfg.Sandbox.ActiveProfile(); !ok {
+		return nil, fmt.Errorf("unknown sandbox.profile %q (define it under sandbox.profiles or use default or strict)", cfg.Sandbox.Profile)
+	}
+	for tool, class := range cfg.Approval.Tools {
+		switch class {
+		case "read", "write", "destructive":
+		default:
+			return nil, fmt.Errorf("invalid approval.tools class %q for %s (expected read, write or destructive)", class, tool)
+		}
+	}
+
+	return &cfg, nil
+}
+
+// read loads the config file and environment with the defaults applied
+func read() (*viper.Viper, error) {
+	v := viper.New()
 	// Config file settings
 	v.SetConfigName("config")
 	v.SetConfigType("yaml")
@@ -46,12 +337,26 @@ func Load() (*Config, error) {
 	v.BindEnv("cnb.token", "CNB_TOKEN")
 	v.BindEnv("cnb.mcp_url", "CNB_MCP_URL")
 	v.BindEnv("cnb.api_base", "CNB_API_BASE")
+	v.BindEnv("cnb.transport", "CNB_MCP_TRANSPORT")
+	v.BindEnv("read_only", "CNB_READ_ONLY")
 
 	// Set defaults
 	v.SetDefault("llm.base_url", "https://api.openai.com/v1")
 	v.SetDefault("llm.model", "gpt-4")
 	v.SetDefault("cnb.mcp_url", "https://mcp.cnb.cool/sse")
 	v.SetDefault("cnb.api_base", "https://api.cnb.cool")
+	v.SetDefault("cnb.transport", "auto")
+	v.SetDefault("timeouts.llm", "2m")
+	v.SetDefault("timeouts.tool", "5m")
+	v.SetDefault("timeouts.turn", "15m")
+	v.SetDefault("timeouts.connect", "30s")
+	v.SetDefault("context.keep_turns", 2)
+	v.SetDefault("tool_output.max_bytes", 16384)
+	v.SetDefault("sandbox.profile", "default")
+	v.SetDefault("audit.enabled", true)
+	v.SetDefault("tools.concurrency", 4)
+	v.SetDefault("tools.max_iterations", 10)
+	v.SetDefault("tools.max_repeats", 3)
 
 	// Try to read config file (optional)
 	if err := v.ReadInConfig(); err != nil {
@@ -60,19 +365,93 @@ func Load() (*Config, error) {
 		}
 		// Config file not found is OK, we'll use env vars
 	}
+	return v, nil
+}
 
-	var cfg Config
-	if err := v.Unmarshal(&cfg); err != nil {
+// SkillDirs returns the skills.dirs setting. Unlike Load it does not require
+// the credentials, so skills can be managed on a machine without them.
+func SkillDirs() ([]string, error) {
+	v, err := read()
+	if err != nil {
+		return nil, err
+	}
+
+	var skills SkillsConfig
+	if err := v.UnmarshalKey("skills", &skills); err != nil {
 		return nil, fmt.Errorf("error unmarshaling config: %w", err)
 	}
+	return skills.Dirs, nil
+}
 
-	// Validate required fields
-	if cfg.LLM.APIKey == "" {
-		return nil, fmt.Errorf("LLM API key is required (set OPENAI_API_KEY or llm.api_key in config)")
+// AuditPath returns the audit.path setting, "" for the default location.
+// Like SkillDirs it does not require the credentials.
+func AuditPath() (string, error) {
+	v, err := read()
+	if err != nil {
+		return "", err
 	}
-	if cfg.CNB.Token == "" {
-		return nil, fmt.Errorf("CNB token is required (set CNB_TOKEN or cnb.token in config)")
+	return v.GetString("audit.path"), nil
+}
+
+// validateHTTPURL checks that raw is an absolute http(s) URL
+func validateHTTPURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%q must be an http or https URL", raw)
 	}
+	if u.Host == "" {
+		return fmt.Errorf("%q has no host", raw)
+	}
+	return nil
+}
 
-	return &cfg, nil
+// validateMCPServers checks names are unique and each server has exactly one way to reach it
+func validateMCPServers(servers []MCPServerConfig) error {
+	seen := map[string]bool{"cnb": true}
+	for i := range servers {
+		s := &servers[i]
+		if !serverNamePattern.MatchString(s.Name) {
+			return fmt.Errorf("mcp_servers[%d]: name %q must be 1-32 letters, digits or '-'", i, s.Name)
+		}
+		if seen[s.Name] {
+			return fmt.Errorf("mcp_servers[%d]: duplicate or reserved name %q", i, s.Name)
+		}
+		seen[s.Name] = true
+
+		hasCommand := strings.TrimSpace(s.Command) != ""
+		hasURL := strings.TrimSpace(s.URL) != ""
+		if hasCommand == hasURL {
+			return fmt.Errorf("mcp_servers[%d] (%s): exactly one of command or url is required", i, s.Name)
+		}
+		if s.ToolPrefix != "" && !toolPrefixPattern.MatchString(s.ToolPrefix) {
+			return fmt.Errorf("mcp_servers[%d] (%s): tool_prefix %q may only contain letters, digits, '_' or '-'", i, s.Name, s.ToolPrefix)
+		}
+
+		for _, kv := range s.Env {
+			if !strings.Contains(kv, "=") {
+				return fmt.Errorf("mcp_servers[%d] (%s): env entry %q must be KEY=VALUE", i, s.Name, kv)
+			}
+		}
+
+		if hasURL {
+			if err := validateHTTPURL(s.URL); err != nil {
+				return fmt.Errorf("mcp_servers[%d] (%s): invalid url: %w", i, s.Name, err)
+			}
+			switch s.Transport {
+			case "":
+				s.Transport = "auto"
+			case "auto", "streamable-http", "sse":
+			default:
+				return fmt.Errorf("mcp_servers[%d] (%s): invalid transport %q", i, s.Name, s.Transport)
+			}
+			s.Token = os.ExpandEnv(s.Token)
+			for k, v := range s.Headers {
+				s.Headers[k] = os.ExpandEnv(v)
+			}
+		}
+	}
+	return nil
 }
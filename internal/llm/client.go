@@ -2,12 +2,14 @@ package llm
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 
 	"github.com/cloudwego/eino-ext/components/model/openai"
 	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/schema"
+	"github.com/eino-contrib/jsonschema"
 )
 
 // Client wraps eino-ext OpenAI client with streaming support
@@ -35,16 +37,17 @@ func NewClient(apiKey, baseURL, modelName string) (*Client, error) {
 }
 
 // Chat sends a chat completion request (non-streaming)
-func (c *Client) Chat(messages []Message, tools []Tool) (*ChatResponse, error) {
-	ctx := context.Background()
-
+func (c *Client) Chat(ctx context.Context, messages []Message, tools []Tool) (*ChatResponse, error) {
 	// Convert messages to eino schema
 	einoMessages := messagesToEino(messages)
 
 	// Prepare options with tools if provided
 	var opts []model.Option
 	if len(tools) > 0 {
-		toolInfos := toolsToEino(tools)
+		toolInfos, err := toolsToEino(tools)
+		if err != nil {
+			return nil, err
+		}
 		opts = append(opts, model.WithTools(toolInfos))
 	}
 
@@ -62,16 +65,17 @@ func (c *Client) Chat(messages []Message, tools []Tool) (*ChatResponse, error) {
 type StreamCallback func(chunk string) error
 
 // ChatStream sends a chat completion request with streaming
-func (c *Client) ChatStream(messages []Message, tools []Tool, callback StreamCallback) (*ChatResponse, error) {
-	ctx := context.Background()
-
+func (c *Client) ChatStream(ctx context.Context, messages []Message, tools []Tool, callback StreamCallback) (*ChatResponse, error) {
 	// Convert messages to eino schema
 	einoMessages := messagesToEino(messages)
 
 	// Prepare options with tools if provided
 	var opts []model.Option
 	if len(tools) > 0 {
-		toolInfos := toolsToEino(tools)
+		toolInfos, err := toolsToEino(tools)
+		if err != nil {
+			return nil, err
+		}
 		opts = append(opts, model.WithTools(toolInfos))
 	}
 
@@ -81,9 +85,15 @@ func (c *Client) ChatStream(messages []Message, tools []Tool, callback StreamCal
 		return nil, fmt.Errorf("stream failed: %w", err)
 	}
 
+	defer reader.Close()
+
 	// Collect all chunks
 	var chunks []*schema.Message
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		chunk, err := reader.Recv()
 		if err == io.EOF {
 			break
@@ -144,20 +154,50 @@ func messagesToEino(messages []Message) []*schema.Message {
 	return result
 }
 
-// toolsToEino converts our Tool format to eino schema.ToolInfo
-// Note: eino will handle the parameter schema internally
-func toolsToEino(tools []Tool) []*schema.ToolInfo {
+// toolsToEino converts our Tool format to eino schema.ToolInfo.
+// Parameters are carried over as a JSON Schema so the provider sees the real argument types.
+func toolsToEino(tools []Tool) ([]*schema.ToolInfo, error) {
 	result := make([]*schema.ToolInfo, len(tools))
 	for i, tool := range tools {
-		result[i] = &schema.ToolInfo{
+		info := &schema.ToolInfo{
 			Name: tool.Function.Name,
 			Desc: tool.Function.Description,
-			Extra: map[string]any{
-				"parameters": tool.Function.Parameters,
-			},
 		}
+		params, err := parametersToJSONSchema(tool.Function.Parameters)
+		if err != nil {
+			return nil, fmt.Errorf("tool %s: %w", tool.Function.Name, err)
+		}
+		if params != nil {
+			info.ParamsOneOf = schema.NewParamsOneOfByJSONSchema(params)
+		}
+		result[i] = info
 	}
-	return result
+	return result, nil
+}
+
+// ValidateParameters reports whether a parameters map can be sent as a JSON Schema
+func ValidateParameters(params map[string]interface{}) error {
+	_, err := parametersToJSONSchema(params)
+	return err
+}
+
+// parametersToJSONSchema converts a parameters map into a typed JSON Schema.
+// Returns nil when the map is empty, and an error when it is not a valid schema.
+func parametersToJSONSchema(params map[string]interface{}) (*jsonschema.Schema, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameters schema: %w", err)
+	}
+
+	var s jsonschema.Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid parameters schema: %w", err)
+	}
+	return &s, nil
 }
 
 // einoToResponse converts eino schema.Message back to our ChatResponse format
@@ -0,0 +1,84 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// mustParams decodes a JSON parameters schema as an MCP server sends it
+func mustParams(t *testing.T, raw string) map[string]interface{} {
+	t.Helper()
+
+	var params map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &params); err != nil {
+		t.Fatal(err)
+	}
+	return params
+}
+
+func TestParametersToJSONSchema(t *testing.T) {
+	cases := []struct {
+		name    string
+		raw     string
+		wantErr bool
+	}{
+		{name: "empty", raw: `{}`},
+		{
+			name: "nested objects",
+			raw: `{"type":"object","properties":{"repo":{"type":"string"},"filter":{"type":"object",` +
+				`"properties":{"labels":{"type":"array","items":{"type":"string"}}},"required":["labels"]}},"required":["repo"]}`,
+		},
+		{
+			name: "enums",
+			raw:  `{"type":"object","properties":{"state":{"type":"string","enum":["open","closed","all"]},"page":{"type":"integer","minimum":1}}}`,
+		},
+		{name: "missing properties", raw: `{"type":"object"}`},
+		{name: "type is not a string", raw: `{"type":5}`, wantErr: true},
+		{name: "properties is not an object", raw: `{"type":"object","properties":"repo"}`, wantErr: true},
+		{name: "required is not a list", raw: `{"type":"object","required":"repo"}`, wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			params := mustParams(t, tc.raw)
+			s, err := parametersToJSONSchema(params)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("Expected an error, got %+v", s)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(params) == 0 {
+				if s != nil {
+					t.Errorf("Expected no schema for empty parameters, got %+v", s)
+				}
+				return
+			}
+
+			// The typed schema must carry everything the server declared
+			got, err := json.Marshal(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var roundTrip map[string]interface{}
+			if err := json.Unmarshal(got, &roundTrip); err != nil {
+				t.Fatal(err)
+			}
+			want, _ := json.Marshal(params)
+			again, _ := json.Marshal(roundTrip)
+			if string(want) != string(again) {
+				t.Errorf("Schema changed in conversion:\nwant %s\ngot  %s", want, again)
+			}
+		})
+	}
+}
+
+func TestToolsToEinoReportsInvalidSchema(t *testing.T) {
+	tools := []Tool{{Type: "function", Function: Function{Name: "broken", Parameters: map[string]interface{}{"type": 5}}}}
+	if _, err := toolsToEino(tools); err == nil {
+		t.Fatal("Expected an error for an undecodable schema")
+	}
+}
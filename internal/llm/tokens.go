@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// messageOverhead approximates the tokens a chat format adds around each message
+const messageOverhead = 4
+
+// EstimateTokens approximates the number of prompt tokens used by messages.
+// It is a heuristic, not a tokenizer: ASCII text counts about four characters
+// per token and other characters (e.g. CJK) about one token each, which errs
+// on the high side for most models.
+func EstimateTokens(messages []Message) int {
+	total := 0
+	for _, m := range messages {
+		total += messageOverhead + estimateText(m.Content)
+		for _, tc := range m.ToolCalls {
+			total += messageOverhead + estimateText(tc.Function.Name) + estimateText(tc.Function.Arguments)
+		}
+	}
+	return total
+}
+
+// EstimateToolTokens approximates the prompt tokens used by tool definitions
+func EstimateToolTokens(tools []Tool) int {
+	if len(tools) == 0 {
+		return 0
+	}
+	data, err := json.Marshal(tools)
+	if err != nil {
+		return 0
+	}
+	return estimateText(string(data))
+}
+
+// estimateText approximates the tokens of a single string
+func estimateText(s string) int {
+	ascii, other := 0, 0
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+		i += size
+	}
+	return (ascii+3)/4 + other
+}
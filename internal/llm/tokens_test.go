@@ -0,0 +1,30 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	if got := EstimateTokens(nil); got != 0 {
+		t.Errorf("Expected 0 tokens for no messages, got %d", got)
+	}
+
+	english := EstimateTokens([]Message{{Role: "user", Content: strings.Repeat("abcd", 100)}})
+	if english != messageOverhead+100 {
+		t.Errorf("Expected %d tokens for 400 ASCII characters, got %d", messageOverhead+100, english)
+	}
+
+	chinese := EstimateTokens([]Message{{Role: "user", Content: strings.Repeat("构建", 50)}})
+	if chinese != messageOverhead+100 {
+		t.Errorf("Expected %d tokens for 100 CJK characters, got %d", messageOverhead+100, chinese)
+	}
+
+	call := Message{Role: "assistant"}
+	call.ToolCalls = make([]ToolCall, 1)
+	call.ToolCalls[0].Function.Name = "list_repositories"
+	call.ToolCalls[0].Function.Arguments = `{"page":1}`
+	if got := EstimateTokens([]Message{call}); got <= 2*messageOverhead {
+		t.Errorf("Expected tool calls to be counted, got %d", got)
+	}
+}
@@ -0,0 +1,432 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Client speaks the MCP protocol to a server over a Transport
+type Client struct {
+	url  string
+	kind string
+	// newTransport builds a transport of the given kind; nil when the
+	// transport was supplied by the caller
+	newTransport func(kind string) Transport
+
+	nextID    atomic.Int64
+	nextToken atomic.Int64
+
+	mu         sync.Mutex
+	transport  Transport
+	serverInfo *InitializeResult
+}
+
+// NewClient creates a new MCP client for the given endpoint.
+// token is sent as a Bearer token on every request when non-empty.
+// transport is one of TransportAuto, TransportStreamableHTTP or TransportSSE;
+// with TransportAuto the transport is negotiated during Initialize.
+func NewClient(endpoint, token, transport string) *Client {
+	return NewClientWithHeader(endpoint, bearerHeader(token), transport)
+}
+
+// NewClientWithHeader is like NewClient but sends arbitrary headers with every request
+func NewClientWithHeader(endpoint string, header http.Header, transport string) *Client {
+	if transport == "" {
+		transport = TransportAuto
+	}
+
+	return &Client{
+		url:  endpoint,
+		kind: transport,
+		newTransport: func(kind string) Transport {
+			if kind == TransportSSE {
+				return NewSSETransport(endpoint, header)
+			}
+			return NewStreamableHTTPTransport(endpoint, header)
+		},
+	}
+}
+
+// NewClientWithTransport creates a client that uses an already configured transport.
+// name identifies the server in error messages.
+func NewClientWithTransport(name string, transport Transport) *Client {
+	kind := "custom"
+	switch transport.(type) {
+	case *StreamableHTTPTransport:
+		kind = TransportStreamableHTTP
+	case *SSETransport:
+		kind = TransportSSE
+	case *StdioTransport:
+		kind = TransportStdio
+	}
+
+	return &Client{
+		url:       name,
+		kind:      kind,
+		transport: transport,
+	}
+}
+
+// URL returns the endpoint the client talks to
+func (c *Client) URL() string {
+	return c.url
+}
+
+// Transport returns the name of the transport in use
+func (c *Client) Transport() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.kind
+}
+
+// ServerInfo returns the result of the initialize handshake, or nil before Initialize
+func (c *Client) ServerInfo() *InitializeResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.serverInfo
+}
+
+// Initialize connects the transport and performs the MCP handshake.
+// With TransportAuto, a URL ending in /sse tries the legacy SSE transport first,
+// any other URL tries Streamable HTTP first; the other one is the fallback.
+func (c *Client) Initialize(ctx context.Context) error {
+	if c.newTransport == nil {
+		if err := c.transport.Connect(ctx); err != nil {
+			return err
+		}
+		return c.handshake(ctx)
+	}
+
+	candidates := []string{c.kind}
+	if c.kind == TransportAuto {
+		candidates = []string{TransportStreamableHTTP, TransportSSE}
+		if u, err := url.Parse(c.url); err == nil && strings.HasSuffix(strings.TrimRight(u.Path, "/"), "/sse") {
+			candidates = []string{TransportSSE, TransportStreamableHTTP}
+		}
+	}
+
+	var errs []error
+	for _, kind := range candidates {
+		transport := c.newTransport(kind)
+
+		err := transport.Connect(ctx)
+		if err == nil {
+			c.mu.Lock()
+			c.transport = transport
+			c.kind = kind
+			c.mu.Unlock()
+
+			err = c.handshake(ctx)
+			if err == nil {
+				return nil
+			}
+		}
+		transport.Close()
+
+		if !errors.Is(err, ErrUnsupportedTransport) {
+			return err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", kind, err))
+	}
+
+	return fmt.Errorf("no supported MCP transport at %s: %w", c.url, errors.Join(errs...))
+}
+
+// handshake sends initialize followed by notifications/initialized
+func (c *Client) handshake(ctx context.Context) error {
+	params := map[string]interface{}{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo": Implementation{
+			Name:    "learn-skills",
+			Version: "0.1.0",
+		},
+	}
+
+	var result InitializeResult
+	if err := c.call(ctx, "initialize", params, &result); err != nil {
+		return fmt.Errorf("initialize failed: %w", err)
+	}
+
+	c.mu.Lock()
+	c.serverInfo = &result
+	transport := c.transport
+	c.mu.Unlock()
+
+	if setter, ok := transport.(protocolVersionSetter); ok {
+		setter.setProtocolVersion(result.ProtocolVersion)
+	}
+
+	if err := c.notify(ctx, "notifications/initialized", nil); err != nil {
+		return fmt.Errorf("initialized notification failed: %w", err)
+	}
+
+	return nil
+}
+
+// ListTools returns all tools offered by the server, following pagination cursors
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+
+	for {
+		var params map[string]interface{}
+		if cursor != "" {
+			params = map[string]interface{}{"cursor": cursor}
+		}
+
+		var result ListToolsResult
+		if err := c.call(ctx, "tools/list", params, &result); err != nil {
+			return nil, fmt.Errorf("tools/list failed: %w", err)
+		}
+
+		tools = append(tools, result.Tools...)
+		if result.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// CallTool invokes a tool by name with structured arguments
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]interface{}) (*CallToolResult, error) {
+	return c.CallToolWithNotices(ctx, name, args, nil)
+}
+
+// CallToolWithNotices invokes a tool and reports progress and log
+// notifications to onNotice while the call runs. onNotice may be nil.
+func (c *Client) CallToolWithNotices(ctx context.Context, name string, args map[string]interface{}, onNotice func(Notice)) (*CallToolResult, error) {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+
+	params := map[string]interface{}{
+		"name":      name,
+		"arguments": args,
+	}
+
+	var notify NotifyFunc
+	if onNotice != nil {
+		token := fmt.Sprintf("progress-%d", c.nextToken.Add(1))
+		params["_meta"] = map[string]interface{}{"progressToken": token}
+		notify = noticeFilter(token, onNotice)
+	}
+
+	var result CallToolResult
+	if err := c.callWithNotify(ctx, "tools/call", params, &result, notify); err != nil {
+		return nil, fmt.Errorf("tools/call %s failed: %w", name, err)
+	}
+
+	return &result, nil
+}
+
+// noticeFilter converts raw notifications into Notices: progress updates
+// for token and all log messages
+func noticeFilter(token string, onNotice func(Notice)) NotifyFunc {
+	return func(method string, params json.RawMessage) {
+		switch method {
+		case "notifications/progress":
+			var p progressParams
+			if err := json.Unmarshal(params, &p); err != nil || fmt.Sprint(p.ProgressToken) != token {
+				return
+			}
+			onNotice(Notice{
+				Method:   method,
+				Progress: p.Progress,
+				Total:    p.Total,
+				Message:  p.Message,
+			})
+		case "notifications/message":
+			var p logMessageParams
+			if err := json.Unmarshal(params, &p); err != nil {
+				return
+			}
+			text, ok := p.Data.(string)
+			if !ok {
+				data, _ := json.Marshal(p.Data)
+				text = string(data)
+			}
+			if p.Logger != "" {
+				text = p.Logger + ": " + text
+			}
+			onNotice(Notice{
+				Method:  method,
+				Level:   p.Level,
+				Message: text,
+			})
+		}
+	}
+}
+
+// HasCapability reports whether the server advertised the named capability
+// (e.g. "tools", "resources", "prompts") during Initialize
+func (c *Client) HasCapability(name string) bool {
+	info := c.ServerInfo()
+	if info == nil {
+		return false
+	}
+	_, ok := info.Capabilities[name]
+	return ok
+}
+
+// ListResources returns all resources offered by the server, following pagination cursors
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	var resources []Resource
+	cursor := ""
+
+	for {
+		var params map[string]interface{}
+		if cursor != "" {
+			params = map[string]interface{}{"cursor": cursor}
+		}
+
+		var result ListResourcesResult
+		if err := c.call(ctx, "resources/list", params, &result); err != nil {
+			return nil, fmt.Errorf("resources/list failed: %w", err)
+		}
+
+		resources = append(resources, result.Resources...)
+		if result.NextCursor == "" {
+			return resources, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// ReadResource reads the resource with the given URI
+func (c *Client) ReadResource(ctx context.Context, uri string) (*ReadResourceResult, error) {
+	var result ReadResourceResult
+	if err := c.call(ctx, "resources/read", map[string]interface{}{"uri": uri}, &result); err != nil {
+		return nil, fmt.Errorf("resources/read %s failed: %w", uri, err)
+	}
+	return &result, nil
+}
+
+// ListPrompts returns all prompts offered by the server, following pagination cursors
+func (c *Client) ListPrompts(ctx context.Context) ([]Prompt, error) {
+	var prompts []Prompt
+	cursor := ""
+
+	for {
+		var params map[string]interface{}
+		if cursor != "" {
+			params = map[string]interface{}{"cursor": cursor}
+		}
+
+		var result ListPromptsResult
+		if err := c.call(ctx, "prompts/list", params, &result); err != nil {
+			return nil, fmt.Errorf("prompts/list failed: %w", err)
+		}
+
+		prompts = append(prompts, result.Prompts...)
+		if result.NextCursor == "" {
+			return prompts, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// GetPrompt expands a prompt template with the given arguments
+func (c *Client) GetPrompt(ctx context.Context, name string, args map[string]string) (*GetPromptResult, error) {
+	params := map[string]interface{}{"name": name}
+	if len(args) > 0 {
+		params["arguments"] = args
+	}
+
+	var result GetPromptResult
+	if err := c.call(ctx, "prompts/get", params, &result); err != nil {
+		return nil, fmt.Errorf("prompts/get %s failed: %w", name, err)
+	}
+	return &result, nil
+}
+
+// Close releases the transport
+func (c *Client) Close() error {
+	transport := c.currentTransport()
+	if transport == nil {
+		return nil
+	}
+	return transport.Close()
+}
+
+// currentTransport returns the transport selected during Initialize
+func (c *Client) currentTransport() Transport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.transport
+}
+
+// call sends a JSON-RPC request and decodes the result into result
+func (c *Client) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	return c.callWithNotify(ctx, method, params, result, nil)
+}
+
+// callWithNotify is like call but passes server notifications received while
+// the request is in flight to notify. If ctx ends first, the server is told
+// via notifications/cancelled so it can stop working on the request.
+func (c *Client) callWithNotify(ctx context.Context, method string, params interface{}, result interface{}, notify NotifyFunc) error {
+	transport := c.currentTransport()
+	if transport == nil {
+		return fmt.Errorf("MCP client is not initialized")
+	}
+
+	id := c.nextID.Add(1)
+	msg, err := transport.Send(ctx, &Request{
+		JSONRPC: "2.0",
+		ID:      id,
+		Method:  method,
+		Params:  params,
+	}, notify)
+	if err != nil {
+		// The initialize request must never be cancelled
+		if ctx.Err() != nil && method != "initialize" {
+			c.cancelRequest(id, ctx.Err())
+		}
+		return err
+	}
+	if msg.Error != nil {
+		return msg.Error
+	}
+
+	if result != nil && len(msg.Result) > 0 {
+		if err := json.Unmarshal(msg.Result, result); err != nil {
+			return fmt.Errorf("failed to decode result: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// cancelRequest sends notifications/cancelled for an abandoned request.
+// Failures are ignored: the request is already abandoned on our side.
+func (c *Client) cancelRequest(id int64, reason error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c.notify(ctx, "notifications/cancelled", map[string]interface{}{
+		"requestId": id,
+		"reason":    reason.Error(),
+	})
+}
+
+// notify sends a JSON-RPC notification
+func (c *Client) notify(ctx context.Context, method string, params interface{}) error {
+	transport := c.currentTransport()
+	if transport == nil {
+		return fmt.Errorf("MCP client is not initialized")
+	}
+
+	return transport.Notify(ctx, &Notification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
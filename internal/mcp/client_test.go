@@ -0,0 +1,572 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestServer starts a fake Streamable HTTP MCP server.
+// When sse is true, responses are wrapped in an event stream.
+func newTestServer(t *testing.T, sse bool) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			ID     *int64          `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Notifications are acknowledged without a body
+		if req.ID == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		if req.Method != "initialize" && r.Header.Get("Mcp-Session-Id") != "session-1" {
+			http.Error(w, "missing session", http.StatusBadRequest)
+			return
+		}
+
+		var result interface{}
+		switch req.Method {
+		case "initialize":
+			w.Header().Set("Mcp-Session-Id", "session-1")
+			result = map[string]interface{}{
+				"protocolVersion": ProtocolVersion,
+				"capabilities": map[string]interface{}{
+					"tools": map[string]interface{}{}, "resources": map[string]interface{}{}, "prompts": map[string]interface{}{},
+				},
+				"serverInfo": map[string]interface{}{"name": "fake", "version": "1.0"},
+			}
+		case "tools/list":
+			result = map[string]interface{}{
+				"tools": []map[string]interface{}{
+					{"name": "cnb_get_repository", "inputSchema": map[string]interface{}{"type": "object"}},
+				},
+			}
+		case "tools/call":
+			var params struct {
+				Name      string                 `json:"name"`
+				Arguments map[string]interface{} `json:"arguments"`
+			}
+			json.Unmarshal(req.Params, &params)
+			result = map[string]interface{}{
+				"content": []map[string]interface{}{
+					{"type": "text", "text": fmt.Sprintf("%s:%v", params.Name, params.Arguments["repo"])},
+				},
+			}
+		case "resources/list":
+			// Two pages, to exercise the cursor
+			var params struct {
+				Cursor string `json:"cursor"`
+			}
+			json.Unmarshal(req.Params, &params)
+			if params.Cursor == "" {
+				result = map[string]interface{}{
+					"resources":  []map[string]interface{}{{"uri": "cnb://demo-app/README.md", "name": "README"}},
+					"nextCursor": "page-2",
+				}
+			} else {
+				result = map[string]interface{}{
+					"resources": []map[string]interface{}{{"uri": "cnb://demo-app/logo.png", "name": "logo", "mimeType": "image/png"}},
+				}
+			}
+		case "resources/read":
+			var params struct {
+				URI string `json:"uri"`
+			}
+			json.Unmarshal(req.Params, &params)
+			result = map[string]interface{}{
+				"contents": []map[string]interface{}{{"uri": params.URI, "mimeType": "text/markdown", "text": "# demo-app"}},
+			}
+		case "prompts/list":
+			result = map[string]interface{}{
+				"prompts": []map[string]interface{}{{
+					"name":      "review",
+					"arguments": []map[string]interface{}{{"name": "pr", "required": true}, {"name": "focus"}},
+				}},
+			}
+		case "prompts/get":
+			var params struct {
+				Name      string            `json:"name"`
+				Arguments map[string]string `json:"arguments"`
+			}
+			json.Unmarshal(req.Params, &params)
+			result = map[string]interface{}{
+				"messages": []map[string]interface{}{
+					{"role": "user", "content": map[string]interface{}{"type": "text", "text": fmt.Sprintf("%s PR %s on %s", params.Name, params.Arguments["pr"], params.Arguments["focus"])}},
+				},
+			}
+		}
+
+		resp, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": *req.ID, "result": result})
+		if sse {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/message\"}\n\n")
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", resp)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resp)
+	}))
+}
+
+func TestClientRoundTrip(t *testing.T) {
+	for _, sse := range []bool{false, true} {
+		t.Run(fmt.Sprintf("sse=%v", sse), func(t *testing.T) {
+			server := newTestServer(t, sse)
+			defer server.Close()
+
+			ctx := context.Background()
+			client := NewClient(server.URL, "test-token", TransportAuto)
+
+			if err := client.Initialize(ctx); err != nil {
+				t.Fatalf("Initialize() failed: %v", err)
+			}
+			if name := client.ServerInfo().ServerInfo.Name; name != "fake" {
+				t.Errorf("Expected server name 'fake', got '%s'", name)
+			}
+
+			tools, err := client.ListTools(ctx)
+			if err != nil {
+				t.Fatalf("ListTools() failed: %v", err)
+			}
+			if len(tools) != 1 || tools[0].Name != "cnb_get_repository" {
+				t.Errorf("Unexpected tools: %+v", tools)
+			}
+
+			result, err := client.CallTool(ctx, "cnb_get_repository", map[string]interface{}{"repo": "demo-app"})
+			if err != nil {
+				t.Fatalf("CallTool() failed: %v", err)
+			}
+			if got := result.Text(); got != "cnb_get_repository:demo-app" {
+				t.Errorf("Expected 'cnb_get_repository:demo-app', got '%s'", got)
+			}
+		})
+	}
+}
+
+func TestClientResourcesAndPrompts(t *testing.T) {
+	server := newTestServer(t, false)
+	defer server.Close()
+
+	ctx := context.Background()
+	client := NewClient(server.URL, "test-token", TransportAuto)
+	if err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize() failed: %v", err)
+	}
+
+	resources, err := client.ListResources(ctx)
+	if err != nil {
+		t.Fatalf("ListResources() failed: %v", err)
+	}
+	if len(resources) != 2 || resources[0].URI != "cnb://demo-app/README.md" || resources[1].MimeType != "image/png" {
+		t.Errorf("Expected both pages of resources, got %+v", resources)
+	}
+
+	read, err := client.ReadResource(ctx, "cnb://demo-app/README.md")
+	if err != nil {
+		t.Fatalf("ReadResource() failed: %v", err)
+	}
+	if got := read.Text(); got != "# demo-app" {
+		t.Errorf("Expected '# demo-app', got '%s'", got)
+	}
+
+	prompts, err := client.ListPrompts(ctx)
+	if err != nil {
+		t.Fatalf("ListPrompts() failed: %v", err)
+	}
+	if len(prompts) != 1 || prompts[0].Name != "review" || len(prompts[0].Arguments) != 2 || !prompts[0].Arguments[0].Required {
+		t.Errorf("Unexpected prompts: %+v", prompts)
+	}
+
+	prompt, err := client.GetPrompt(ctx, "review", map[string]string{"pr": "42", "focus": "tests"})
+	if err != nil {
+		t.Fatalf("GetPrompt() failed: %v", err)
+	}
+	if len(prompt.Messages) != 1 || prompt.Messages[0].Content.Text != "review PR 42 on tests" {
+		t.Errorf("Unexpected prompt messages: %+v", prompt.Messages)
+	}
+}
+
+func TestClientUnauthorized(t *testing.T) {
+	server := newTestServer(t, false)
+	defer server.Close()
+
+	client := NewClient(server.URL, "wrong-token", TransportAuto)
+	if err := client.Initialize(context.Background()); err == nil {
+		t.Fatal("Expected Initialize() to fail with a wrong token")
+	}
+}
+
+// newLegacySSEServer starts a fake HTTP+SSE MCP server: GET on streamPath opens
+// the event stream, POSTs to /messages are answered on that stream
+func newLegacySSEServer(t *testing.T, streamPath string) *httptest.Server {
+	t.Helper()
+
+	events := make(chan string, 16)
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(streamPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: endpoint\ndata: /messages?session=1\n\n")
+		w.(http.Flusher).Flush()
+
+		for {
+			select {
+			case data := <-events:
+				fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     *int64 `json:"id"`
+			Method string `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		if req.ID == nil {
+			return
+		}
+
+		var result interface{}
+		switch req.Method {
+		case "initialize":
+			result = map[string]interface{}{
+				"protocolVersion": "2024-11-05",
+				"capabilities":    map[string]interface{}{},
+				"serverInfo":      map[string]interface{}{"name": "legacy", "version": "1.0"},
+			}
+		case "tools/list":
+			result = map[string]interface{}{"tools": []map[string]interface{}{{"name": "lint"}}}
+		}
+		resp, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": *req.ID, "result": result})
+		events <- string(resp)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestClientLegacySSE(t *testing.T) {
+	cases := []struct {
+		name       string
+		streamPath string
+		transport  string
+	}{
+		{"auto by URL", "/sse", TransportAuto},
+		{"auto fallback", "/events", TransportAuto},
+		{"explicit", "/events", TransportSSE},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newLegacySSEServer(t, tc.streamPath)
+			defer server.Close()
+
+			ctx := context.Background()
+			client := NewClient(server.URL+tc.streamPath, "", tc.transport)
+			defer client.Close()
+
+			if err := client.Initialize(ctx); err != nil {
+				t.Fatalf("Initialize() failed: %v", err)
+			}
+			if client.Transport() != TransportSSE {
+				t.Errorf("Expected transport %s, got %s", TransportSSE, client.Transport())
+			}
+
+			tools, err := client.ListTools(ctx)
+			if err != nil {
+				t.Fatalf("ListTools() failed: %v", err)
+			}
+			if len(tools) != 1 || tools[0].Name != "lint" {
+				t.Errorf("Unexpected tools: %+v", tools)
+			}
+		})
+	}
+}
+
+// serverRequests are sent by the fake servers to the client: a ping with a
+// string ID and a request the client does not support
+var serverRequests = []string{
+	`{"jsonrpc":"2.0","id":"ping-1","method":"ping"}`,
+	`{"jsonrpc":"2.0","id":101,"method":"sampling/createMessage","params":{}}`,
+}
+
+// checkServerRequestReplies waits for the replies to serverRequests
+func checkServerRequestReplies(t *testing.T, replies <-chan Response) {
+	t.Helper()
+
+	got := map[string]Response{}
+	for len(got) < len(serverRequests) {
+		select {
+		case resp := <-replies:
+			got[string(resp.ID)] = resp
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected replies to both server requests, got %+v", got)
+		}
+	}
+
+	if ping := got[`"ping-1"`]; ping.Error != nil || string(ping.Result) != "{}" {
+		t.Errorf("Expected an empty result to ping, got %+v", ping)
+	}
+	if other := got["101"]; other.Error == nil || other.Error.Code != codeMethodNotFound {
+		t.Errorf("Expected method not found, got %+v", other)
+	}
+}
+
+func TestSSEAnswersServerRequests(t *testing.T) {
+	replies := make(chan Response, 2)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: endpoint\ndata: /messages\n\n")
+		for _, req := range serverRequests {
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", req)
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		var resp Response
+		json.NewDecoder(r.Body).Decode(&resp)
+		w.WriteHeader(http.StatusAccepted)
+		replies <- resp
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	transport := NewSSETransport(server.URL+"/sse", nil)
+	if err := transport.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer transport.Close()
+
+	checkServerRequestReplies(t, replies)
+}
+
+func TestStreamableHTTPAnswersServerRequests(t *testing.T) {
+	replies := make(chan Response, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg Response
+		json.NewDecoder(r.Body).Decode(&msg)
+		if msg.Method == "" {
+			w.WriteHeader(http.StatusAccepted)
+			replies <- msg
+			return
+		}
+
+		// Server requests arrive on the stream ahead of the response
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, req := range serverRequests {
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", req)
+		}
+		fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":%s,\"result\":{}}\n\n", msg.ID)
+	}))
+	defer server.Close()
+
+	transport := NewStreamableHTTPTransport(server.URL, nil)
+	resp, err := transport.Send(context.Background(), &Request{JSONRPC: "2.0", ID: 7, Method: "tools/list"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.ID) != "7" {
+		t.Errorf("Expected the response to request 7, got %+v", resp)
+	}
+
+	checkServerRequestReplies(t, replies)
+}
+
+func TestStreamableHTTPCloseDoesNotHang(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			<-release // never answer the session DELETE
+			return
+		}
+		w.Header().Set("Mcp-Session-Id", "session-1")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":{}}`)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	defer func(d time.Duration) { closeTimeout = d }(closeTimeout)
+	closeTimeout = 100 * time.Millisecond
+
+	transport := NewStreamableHTTPTransport(server.URL, nil)
+	if _, err := transport.Send(context.Background(), &Request{JSONRPC: "2.0", ID: 1, Method: "initialize"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- transport.Close() }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected the DELETE to time out, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close() hung on a stalled server")
+	}
+}
+
+func TestClientNoSupportedTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "", TransportAuto)
+	err := client.Initialize(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "no supported MCP transport") {
+		t.Fatalf("Expected transport negotiation error, got %v", err)
+	}
+}
+
+func TestClientSSEConnectTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release // never send the response headers
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	client := NewClient(server.URL+"/sse", "", TransportSSE)
+	defer client.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- client.Initialize(ctx) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected deadline exceeded, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Initialize() ignored the context deadline")
+	}
+}
+
+func TestCallToolNoticesAndCancel(t *testing.T) {
+	cancelled := make(chan string, 1)
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     *int64 `json:"id"`
+			Method string `json:"method"`
+			Params struct {
+				Name      string `json:"name"`
+				RequestID int64  `json:"requestId"`
+				Meta      struct {
+					ProgressToken string `json:"progressToken"`
+				} `json:"_meta"`
+			} `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		if req.ID == nil {
+			if req.Method == "notifications/cancelled" {
+				cancelled <- fmt.Sprint(req.Params.RequestID)
+			}
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		var result interface{}
+		switch req.Method {
+		case "initialize":
+			result = map[string]interface{}{"protocolVersion": ProtocolVersion, "capabilities": map[string]interface{}{}}
+		case "tools/call":
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "data: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\",\"params\":{\"progressToken\":%q,\"progress\":1,\"total\":2,\"message\":\"half\"}}\n\n", req.Params.Meta.ProgressToken)
+			fmt.Fprintf(w, "data: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\",\"params\":{\"progressToken\":\"other\",\"progress\":9}}\n\n")
+			fmt.Fprintf(w, "data: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/message\",\"params\":{\"level\":\"info\",\"data\":\"fetching logs\"}}\n\n")
+			w.(http.Flusher).Flush()
+			if req.Params.Name == "slow" {
+				select {
+				case <-release:
+				case <-r.Context().Done():
+				}
+				return
+			}
+			resp, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": *req.ID, "result": map[string]interface{}{"content": []interface{}{}}})
+			fmt.Fprintf(w, "data: %s\n\n", resp)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": *req.ID, "result": result})
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewClient(server.URL, "", TransportStreamableHTTP)
+	if err := client.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize() failed: %v", err)
+	}
+
+	var notices []Notice
+	if _, err := client.CallToolWithNotices(context.Background(), "fast", nil, func(n Notice) {
+		notices = append(notices, n)
+	}); err != nil {
+		t.Fatalf("CallToolWithNotices() failed: %v", err)
+	}
+	if len(notices) != 2 {
+		t.Fatalf("Expected 2 notices (own progress + log), got %+v", notices)
+	}
+	if notices[0].Progress != 1 || notices[0].Total != 2 || notices[0].Message != "half" {
+		t.Errorf("Unexpected progress notice: %+v", notices[0])
+	}
+	if notices[1].Level != "info" || notices[1].Message != "fetching logs" {
+		t.Errorf("Unexpected log notice: %+v", notices[1])
+	}
+
+	// Cancelling the context abandons the call and tells the server
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := client.CallToolWithNotices(ctx, "slow", nil, func(n Notice) {
+		cancel()
+	})
+	if err == nil {
+		t.Fatal("Expected cancelled call to fail")
+	}
+	select {
+	case id := <-cancelled:
+		if id == "" || id == "0" {
+			t.Errorf("Expected a request ID in notifications/cancelled, got %q", id)
+		}
+	default:
+		t.Error("Expected notifications/cancelled to be sent")
+	}
+}
@@ -0,0 +1,172 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// dispatcher routes messages read from a long-lived connection to the
+// requests waiting for them. It is shared by the SSE and stdio transports.
+type dispatcher struct {
+	mu      sync.Mutex
+	pending map[int64]*pendingRequest
+	closed  chan struct{}
+	err     error
+}
+
+// pendingRequest is a request waiting for its response
+type pendingRequest struct {
+	ch     chan *Response
+	notify NotifyFunc
+	token  string // Progress token the request was sent with, "" if none
+}
+
+func newDispatcher() *dispatcher {
+	return &dispatcher{
+		pending: make(map[int64]*pendingRequest),
+		closed:  make(chan struct{}),
+	}
+}
+
+// register returns the channel on which the response to req will be delivered.
+// notify receives server notifications while the request is pending.
+func (d *dispatcher) register(req *Request, notify NotifyFunc) chan *Response {
+	ch := make(chan *Response, 1)
+	d.mu.Lock()
+	d.pending[req.ID] = &pendingRequest{ch: ch, notify: notify, token: progressToken(req)}
+	d.mu.Unlock()
+	return ch
+}
+
+// progressToken returns the progress token in the _meta of req's params, "" if none
+func progressToken(req *Request) string {
+	data, err := json.Marshal(req.Params)
+	if err != nil {
+		return ""
+	}
+	var params struct {
+		Meta struct {
+			ProgressToken interface{} `json:"progressToken"`
+		} `json:"_meta"`
+	}
+	if json.Unmarshal(data, &params) != nil || params.Meta.ProgressToken == nil {
+		return ""
+	}
+	return fmt.Sprint(params.Meta.ProgressToken)
+}
+
+// unregister forgets a request that has completed or was abandoned
+func (d *dispatcher) unregister(id int64) {
+	d.mu.Lock()
+	delete(d.pending, id)
+	d.mu.Unlock()
+}
+
+// codeMethodNotFound is the JSON-RPC error code for an unknown method
+const codeMethodNotFound = -32601
+
+// deliver decodes an incoming message. Responses go to their waiter. A
+// progress notification goes to the request that sent its token; any other
+// notification goes to the oldest pending request that listens, so it is
+// reported once per connection rather than once per concurrent call. For a
+// server request it returns the reply the caller must send back: an empty
+// result to a ping, "method not found" to anything else. It returns nil otherwise.
+func (d *dispatcher) deliver(data []byte) *Response {
+	var msg Response
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil
+	}
+
+	if msg.Method != "" {
+		if msg.hasID() {
+			return serverRequestReply(&msg)
+		}
+		if notify := d.notifier(&msg); notify != nil {
+			notify(msg.Method, msg.Params)
+		}
+		return nil
+	}
+
+	var id int64
+	if json.Unmarshal(msg.ID, &id) != nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	p, ok := d.pending[id]
+	d.mu.Unlock()
+	if ok {
+		p.ch <- &msg
+	}
+	return nil
+}
+
+// notifier returns the notify function of the pending request a
+// notification belongs to, nil if no pending request takes it
+func (d *dispatcher) notifier(msg *Response) NotifyFunc {
+	token := ""
+	if msg.Method == "notifications/progress" {
+		var p progressParams
+		if json.Unmarshal(msg.Params, &p) != nil || p.ProgressToken == nil {
+			return nil
+		}
+		token = fmt.Sprint(p.ProgressToken)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var notify NotifyFunc
+	oldest := int64(math.MaxInt64)
+	for id, p := range d.pending {
+		if p.notify == nil {
+			continue
+		}
+		if token != "" {
+			if p.token == token {
+				return p.notify
+			}
+			continue
+		}
+		if id < oldest {
+			oldest, notify = id, p.notify
+		}
+	}
+	return notify
+}
+
+// serverRequestReply answers a request the server sent to the client, echoing
+// its ID unchanged. Only ping is supported; the client offers no sampling,
+// roots or elicitation.
+func serverRequestReply(req *Response) *Response {
+	reply := &Response{JSONRPC: "2.0", ID: req.ID}
+	if req.Method == "ping" {
+		reply.Result = json.RawMessage("{}")
+	} else {
+		reply.Error = &RPCError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+	}
+	return reply
+}
+
+// fail marks the connection as closed; waiting and future requests get err
+func (d *dispatcher) fail(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	select {
+	case <-d.closed:
+		return
+	default:
+	}
+	d.err = err
+	close(d.closed)
+}
+
+// closedErr returns the error the connection was closed with
+func (d *dispatcher) closedErr() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return fmt.Errorf("connection closed: %v", d.err)
+}
@@ -0,0 +1,36 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestDispatcherRoutesNotifications(t *testing.T) {
+	d := newDispatcher()
+	got := map[int64][]string{}
+	for id := int64(1); id <= 3; id++ {
+		params := map[string]interface{}{
+			"name":  "work",
+			"_meta": map[string]interface{}{"progressToken": fmt.Sprintf("progress-%d", id)},
+		}
+		d.register(&Request{JSONRPC: "2.0", ID: id, Method: "tools/call", Params: params}, func(method string, params json.RawMessage) {
+			got[id] = append(got[id], method)
+		})
+	}
+	d.register(&Request{JSONRPC: "2.0", ID: 4, Method: "tools/list"}, nil)
+
+	d.deliver([]byte(`{"jsonrpc":"2.0","method":"notifications/progress","params":{"progressToken":"progress-2","progress":1}}`))
+	d.deliver([]byte(`{"jsonrpc":"2.0","method":"notifications/progress","params":{"progressToken":"progress-9","progress":1}}`))
+	d.deliver([]byte(`{"jsonrpc":"2.0","method":"notifications/message","params":{"level":"info","data":"hi"}}`))
+
+	if len(got[2]) != 1 || got[2][0] != "notifications/progress" {
+		t.Errorf("Expected the progress update only for request 2, got %v", got)
+	}
+	if len(got[1]) != 1 || got[1][0] != "notifications/message" {
+		t.Errorf("Expected the log message once, for the oldest request, got %v", got)
+	}
+	if len(got[3]) != 0 {
+		t.Errorf("Expected nothing for request 3, got %v", got[3])
+	}
+}
@@ -0,0 +1,208 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+)
+
+// Server is an MCP client registered with a Manager
+type Server struct {
+	Name   string
+	Prefix string // Prepended to tool names to keep them unique across servers
+	Client *Client
+}
+
+// route maps an exposed tool name back to the server and original tool
+type route struct {
+	server *Server
+	tool   Tool
+}
+
+// Manager aggregates several MCP servers behind a single tool namespace
+type Manager struct {
+	servers []*Server
+	tools   []Tool // Tools with exposed (prefixed) names, in server order
+	routes  map[string]route
+}
+
+// NewManager creates an empty manager
+func NewManager() *Manager {
+	return &Manager{
+		routes: make(map[string]route),
+	}
+}
+
+// Add registers a server. Its tools are exposed as prefix + tool name.
+func (m *Manager) Add(name, prefix string, client *Client) {
+	m.servers = append(m.servers, &Server{
+		Name:   name,
+		Prefix: prefix,
+		Client: client,
+	})
+}
+
+// Servers returns the registered servers in registration order
+func (m *Manager) Servers() []*Server {
+	return m.servers
+}
+
+// Server returns the registered server with the given name
+func (m *Manager) Server(name string) (*Server, bool) {
+	for _, s := range m.servers {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// Initialize connects every server and collects their tools.
+// Two servers exposing the same (prefixed) tool name is an error.
+func (m *Manager) Initialize(ctx context.Context) error {
+	m.tools = nil
+	m.routes = make(map[string]route)
+
+	for _, s := range m.servers {
+		if err := s.Client.Initialize(ctx); err != nil {
+			return fmt.Errorf("MCP server %q: %w", s.Name, err)
+		}
+
+		tools, err := s.Client.ListTools(ctx)
+		if err != nil {
+			return fmt.Errorf("MCP server %q: %w", s.Name, err)
+		}
+
+		for _, tool := range tools {
+			exposed := s.Prefix + tool.Name
+			if existing, ok := m.routes[exposed]; ok {
+				return fmt.Errorf("MCP server %q: tool %s collides with server %q", s.Name, exposed, existing.server.Name)
+			}
+			m.routes[exposed] = route{server: s, tool: tool}
+
+			tool.Name = exposed
+			m.tools = append(m.tools, tool)
+		}
+	}
+
+	return nil
+}
+
+// Tools returns all tools with their exposed names
+func (m *Manager) Tools() []Tool {
+	return m.tools
+}
+
+// Lookup resolves an exposed tool name to its server and original tool name
+func (m *Manager) Lookup(name string) (server string, tool string, ok bool) {
+	r, ok := m.routes[name]
+	if !ok {
+		return "", "", false
+	}
+	return r.server.Name, r.tool.Name, true
+}
+
+// Annotations returns the behaviour hints of a tool by its exposed name,
+// nil when the tool is unknown or its server gave none
+func (m *Manager) Annotations(name string) *ToolAnnotations {
+	return m.routes[name].tool.Annotations
+}
+
+// CallTool invokes a tool by its exposed name on the server that provides it.
+// Progress and log notifications are reported to onNotice, which may be nil.
+func (m *Manager) CallTool(ctx context.Context, name string, args map[string]interface{}, onNotice func(Notice)) (*CallToolResult, error) {
+	r, ok := m.routes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown MCP tool: %s", name)
+	}
+	return r.server.Client.CallToolWithNotices(ctx, r.tool.Name, args, onNotice)
+}
+
+// ServerResource is a resource together with the server that offers it
+type ServerResource struct {
+	Server string `json:"server"`
+	Resource
+}
+
+// ServerPrompt is a prompt together with the server that offers it
+type ServerPrompt struct {
+	Server string `json:"server"`
+	Prompt
+}
+
+// SupportsResources reports whether any server offers resources
+func (m *Manager) SupportsResources() bool {
+	for _, s := range m.servers {
+		if s.Client.HasCapability("resources") {
+			return true
+		}
+	}
+	return false
+}
+
+// ListResources lists the resources of every server that offers them.
+// When server is non-empty only that server is queried.
+func (m *Manager) ListResources(ctx context.Context, server string) ([]ServerResource, error) {
+	var result []ServerResource
+	for _, s := range m.servers {
+		if (server != "" && s.Name != server) || !s.Client.HasCapability("resources") {
+			continue
+		}
+
+		resources, err := s.Client.ListResources(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("MCP server %q: %w", s.Name, err)
+		}
+		for _, r := range resources {
+			result = append(result, ServerResource{Server: s.Name, Resource: r})
+		}
+	}
+	return result, nil
+}
+
+// ReadResource reads a resource from the named server
+func (m *Manager) ReadResource(ctx context.Context, server, uri string) (*ReadResourceResult, error) {
+	s, ok := m.Server(server)
+	if !ok {
+		return nil, fmt.Errorf("unknown MCP server: %s", server)
+	}
+	if !s.Client.HasCapability("resources") {
+		return nil, fmt.Errorf("MCP server %q does not offer resources", server)
+	}
+	return s.Client.ReadResource(ctx, uri)
+}
+
+// ListPrompts lists the prompts of every server that offers them
+func (m *Manager) ListPrompts(ctx context.Context) ([]ServerPrompt, error) {
+	var result []ServerPrompt
+	for _, s := range m.servers {
+		if !s.Client.HasCapability("prompts") {
+			continue
+		}
+
+		prompts, err := s.Client.ListPrompts(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("MCP server %q: %w", s.Name, err)
+		}
+		for _, p := range prompts {
+			result = append(result, ServerPrompt{Server: s.Name, Prompt: p})
+		}
+	}
+	return result, nil
+}
+
+// GetPrompt expands a prompt on the named server
+func (m *Manager) GetPrompt(ctx context.Context, server, name string, args map[string]string) (*GetPromptResult, error) {
+	s, ok := m.Server(server)
+	if !ok {
+		return nil, fmt.Errorf("unknown MCP server: %s", server)
+	}
+	return s.Client.GetPrompt(ctx, name, args)
+}
+
+// Close closes every server connection
+func (m *Manager) Close() error {
+	for _, s := range m.servers {
+		s.Client.Close()
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// TestHelperStdioServer is not a real test: it is re-executed by the stdio
+// tests as a child process that serves MCP over stdin/stdout
+func TestHelperStdioServer(t *testing.T) {
+	if os.Getenv("MCP_HELPER_STDIO_SERVER") != "1" {
+		t.Skip("helper process")
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var req struct {
+			ID     *int64 `json:"id"`
+			Method string `json:"method"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil || req.ID == nil {
+			continue
+		}
+
+		var result interface{}
+		switch req.Method {
+		case "initialize":
+			result = map[string]interface{}{
+				"protocolVersion": ProtocolVersion,
+				"capabilities":    map[string]interface{}{},
+				"serverInfo":      map[string]interface{}{"name": "lint-bot", "version": "1.0"},
+			}
+		case "tools/list":
+			result = map[string]interface{}{"tools": []map[string]interface{}{{
+				"name":        "cnb_get_repository",
+				"annotations": map[string]interface{}{"readOnlyHint": true},
+			}}}
+		case "tools/call":
+			result = map[string]interface{}{"content": []map[string]interface{}{{"type": "text", "text": "lint ok"}}}
+		}
+		resp, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": *req.ID, "result": result})
+		fmt.Println(string(resp))
+	}
+	os.Exit(0)
+}
+
+// newHelperStdioClient returns a client for the helper stdio server
+func newHelperStdioClient(name string) *Client {
+	transport := NewStdioTransport(os.Args[0], []string{"-test.run=^TestHelperStdioServer$"}, []string{"MCP_HELPER_STDIO_SERVER=1"}, "")
+	return NewClientWithTransport(name, transport)
+}
+
+func TestManagerNamespacesTools(t *testing.T) {
+	server := newTestServer(t, false)
+	defer server.Close()
+
+	manager := NewManager()
+	manager.Add("cnb", "", NewClient(server.URL, "test-token", TransportAuto))
+	manager.Add("lint", "lint__", newHelperStdioClient("lint"))
+	defer manager.Close()
+
+	ctx := context.Background()
+	if err := manager.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize() failed: %v", err)
+	}
+
+	// Both servers offer cnb_get_repository; the prefix keeps them apart
+	names := map[string]bool{}
+	for _, tool := range manager.Tools() {
+		names[tool.Name] = true
+	}
+	if !names["cnb_get_repository"] || !names["lint__cnb_get_repository"] {
+		t.Fatalf("Unexpected tool names: %v", names)
+	}
+
+	serverName, toolName, ok := manager.Lookup("lint__cnb_get_repository")
+	if !ok || serverName != "lint" || toolName != "cnb_get_repository" {
+		t.Errorf("Lookup() = %q, %q, %v", serverName, toolName, ok)
+	}
+	if hints := manager.Annotations("lint__cnb_get_repository"); hints == nil || hints.ReadOnlyHint == nil || !*hints.ReadOnlyHint {
+		t.Errorf("Expected the readOnlyHint annotation to be kept, got %+v", hints)
+	}
+	if hints := manager.Annotations("missing"); hints != nil {
+		t.Errorf("Expected no annotations for an unknown tool, got %+v", hints)
+	}
+
+	result, err := manager.CallTool(ctx, "lint__cnb_get_repository", nil, nil)
+	if err != nil {
+		t.Fatalf("CallTool() failed: %v", err)
+	}
+	if got := result.Text(); got != "lint ok" {
+		t.Errorf("Expected 'lint ok', got '%s'", got)
+	}
+}
+
+func TestManagerRejectsCollisions(t *testing.T) {
+	manager := NewManager()
+	manager.Add("a", "", newHelperStdioClient("a"))
+	manager.Add("b", "", newHelperStdioClient("b"))
+	defer manager.Close()
+
+	if err := manager.Initialize(context.Background()); err == nil {
+		t.Fatal("Expected Initialize() to reject colliding tool names")
+	}
+}
@@ -0,0 +1,61 @@
+package mcp
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// sseEvent is a single Server-Sent Events message
+type sseEvent struct {
+	Event string
+	Data  string
+	ID    string
+}
+
+// readSSEEvent reads the next event from an SSE stream.
+// Comment lines are skipped and multi-line data fields are joined with newlines.
+func readSSEEvent(r *bufio.Reader) (*sseEvent, error) {
+	var ev sseEvent
+	var data []string
+	seen := false
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF && seen {
+				ev.Data = strings.Join(data, "\n")
+				return &ev, nil
+			}
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		// Blank line dispatches the event
+		if line == "" {
+			if !seen {
+				continue
+			}
+			ev.Data = strings.Join(data, "\n")
+			return &ev, nil
+		}
+
+		// Comment line
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		seen = true
+
+		switch field {
+		case "event":
+			ev.Event = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			ev.ID = value
+		}
+	}
+}
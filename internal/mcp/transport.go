@@ -0,0 +1,51 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Transport kinds. NewClient accepts auto, streamable-http and sse;
+// stdio servers are created with NewStdioTransport.
+const (
+	TransportAuto           = "auto"
+	TransportStreamableHTTP = "streamable-http"
+	TransportSSE            = "sse"
+	TransportStdio          = "stdio"
+)
+
+// replyTimeout bounds posting the reply to a server request
+const replyTimeout = 30 * time.Second
+
+// ErrUnsupportedTransport indicates the endpoint does not speak the attempted MCP transport
+var ErrUnsupportedTransport = errors.New("endpoint does not speak the requested MCP transport")
+
+// Transport carries JSON-RPC messages between the client and an MCP server
+type Transport interface {
+	// Connect establishes the underlying connection, if the transport needs one
+	Connect(ctx context.Context) error
+	// Send sends a request and waits for the response with the same ID.
+	// Server notifications received while waiting are passed to notify, if non-nil.
+	Send(ctx context.Context, req *Request, notify NotifyFunc) (*Response, error)
+	// Notify sends a notification that expects no response
+	Notify(ctx context.Context, n *Notification) error
+	// Close releases the connection and any server-side session
+	Close() error
+}
+
+// protocolVersionSetter is implemented by transports that must echo the
+// negotiated protocol version on subsequent requests
+type protocolVersionSetter interface {
+	setProtocolVersion(version string)
+}
+
+// bearerHeader returns request headers carrying token as a Bearer credential
+func bearerHeader(token string) http.Header {
+	header := http.Header{}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	return header
+}
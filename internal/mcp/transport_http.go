@@ -0,0 +1,231 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// StreamableHTTPTransport implements the Streamable HTTP transport: every message
+// is POSTed to a single endpoint, which answers with JSON or an SSE stream
+type StreamableHTTPTransport struct {
+	url        string
+	header     http.Header
+	httpClient *http.Client
+
+	mu              sync.Mutex
+	sessionID       string
+	protocolVersion string
+}
+
+// NewStreamableHTTPTransport creates a Streamable HTTP transport for url.
+// header is added to every request.
+func NewStreamableHTTPTransport(url string, header http.Header) *StreamableHTTPTransport {
+	return &StreamableHTTPTransport{
+		url:    url,
+		header: header,
+		// No client timeout: each call is bounded by its context (timeouts.tool)
+		httpClient: &http.Client{},
+	}
+}
+
+// Connect is a no-op: Streamable HTTP has no long-lived connection
+func (t *StreamableHTTPTransport) Connect(ctx context.Context) error {
+	return nil
+}
+
+// Send POSTs a request and reads its response
+func (t *StreamableHTTPTransport) Send(ctx context.Context, req *Request, notify NotifyFunc) (*Response, error) {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return readResponse(resp, req.ID, notify, t.reply)
+}
+
+// Notify POSTs a notification
+func (t *StreamableHTTPTransport) Notify(ctx context.Context, n *Notification) error {
+	resp, err := t.post(ctx, n)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	return nil
+}
+
+// closeTimeout bounds the request that ends the server-side session
+var closeTimeout = 5 * time.Second
+
+// Close terminates the server-side session if one was established
+func (t *StreamableHTTPTransport) Close() error {
+	if t.currentSessionID() == "" {
+		return nil
+	}
+
+	// A stalled server must not hold up exit
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.url, nil)
+	if err != nil {
+		return err
+	}
+	t.setHeaders(req)
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// reply POSTs the answer to a server request received on a response stream;
+// a failure is left for the server to time out
+func (t *StreamableHTTPTransport) reply(msg *Response) {
+	ctx, cancel := context.WithTimeout(context.Background(), replyTimeout)
+	defer cancel()
+
+	resp, err := t.post(ctx, msg)
+	if err != nil {
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+func (t *StreamableHTTPTransport) setProtocolVersion(version string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.protocolVersion = version
+}
+
+// post sends a JSON-RPC message and returns the raw HTTP response
+func (t *StreamableHTTPTransport) post(ctx context.Context, message interface{}) (*http.Response, error) {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	t.setHeaders(req)
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot reach MCP endpoint %s: %w", t.url, err)
+	}
+
+	// Before a session exists, these statuses mean the URL is not a Streamable HTTP endpoint
+	if (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed) && t.currentSessionID() == "" {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s returned HTTP %d to POST", ErrUnsupportedTransport, t.url, resp.StatusCode)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		defer resp.Body.Close()
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("MCP server returned HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+
+	if sessionID := resp.Header.Get("Mcp-Session-Id"); sessionID != "" {
+		t.mu.Lock()
+		t.sessionID = sessionID
+		t.mu.Unlock()
+	}
+
+	return resp, nil
+}
+
+// currentSessionID returns the session ID assigned by the server, if any
+func (t *StreamableHTTPTransport) currentSessionID() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sessionID
+}
+
+// setHeaders adds configured, session and protocol headers to a request
+func (t *StreamableHTTPTransport) setHeaders(req *http.Request) {
+	for key, values := range t.header {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	if t.protocolVersion != "" {
+		req.Header.Set("MCP-Protocol-Version", t.protocolVersion)
+	}
+}
+
+// readResponse extracts the response with the given ID from an HTTP response.
+// The body is either a single JSON object or an SSE stream of JSON-RPC messages;
+// notifications interleaved in the stream are passed to notify, and the
+// answers to server requests in it are sent with reply.
+func readResponse(resp *http.Response, id int64, notify NotifyFunc, reply func(*Response)) (*Response, error) {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+
+	if mediaType == "text/event-stream" {
+		reader := bufio.NewReader(resp.Body)
+		for {
+			ev, err := readSSEEvent(reader)
+			if err == io.EOF {
+				return nil, fmt.Errorf("stream ended before response to request %d", id)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read event stream: %w", err)
+			}
+			if ev.Data == "" {
+				continue
+			}
+
+			var msg Response
+			if err := json.Unmarshal([]byte(ev.Data), &msg); err != nil {
+				return nil, fmt.Errorf("invalid JSON-RPC message in stream: %w", err)
+			}
+			if msg.Method != "" {
+				if msg.hasID() {
+					// Sent aside so the stream keeps being read while the reply is POSTed
+					go reply(serverRequestReply(&msg))
+				} else if notify != nil {
+					notify(msg.Method, msg.Params)
+				}
+				continue
+			}
+			if !msg.isResponseTo(id) {
+				continue
+			}
+			return &msg, nil
+		}
+	}
+
+	if mediaType != "application/json" {
+		return nil, fmt.Errorf("%w: unexpected Content-Type %q", ErrUnsupportedTransport, resp.Header.Get("Content-Type"))
+	}
+
+	var msg Response
+	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+		return nil, fmt.Errorf("invalid JSON-RPC response: %w", err)
+	}
+	return &msg, nil
+}
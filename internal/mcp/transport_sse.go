@@ -0,0 +1,236 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// SSETransport implements the legacy HTTP+SSE transport: the client holds a GET
+// event stream open, the server announces a POST endpoint via an "endpoint" event,
+// and responses to POSTed requests arrive as "message" events on the stream
+type SSETransport struct {
+	url        string
+	header     http.Header
+	httpClient *http.Client
+
+	dispatch *dispatcher
+
+	mu       sync.Mutex
+	endpoint string
+	cancel   context.CancelFunc
+}
+
+// NewSSETransport creates a legacy SSE transport for url.
+// header is added to every request.
+func NewSSETransport(url string, header http.Header) *SSETransport {
+	return &SSETransport{
+		url:        url,
+		header:     header,
+		httpClient: &http.Client{},
+		dispatch:   newDispatcher(),
+	}
+}
+
+// Connect opens the event stream and waits for the server to announce its POST endpoint
+func (t *SSETransport) Connect(ctx context.Context) error {
+	// The stream outlives ctx, which only bounds the wait for the endpoint event
+	streamCtx, cancel := context.WithCancel(context.Background())
+
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, t.url, nil)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	t.setHeaders(req)
+
+	// Until the response headers arrive the request is also bounded by ctx
+	stop := context.AfterFunc(ctx, cancel)
+	resp, err := t.httpClient.Do(req)
+	if !stop() {
+		if err == nil {
+			resp.Body.Close()
+		}
+		cancel()
+		return ctx.Err()
+	}
+	if err != nil {
+		cancel()
+		return fmt.Errorf("cannot reach MCP endpoint %s: %w", t.url, err)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.StatusCode != http.StatusOK || mediaType != "text/event-stream" {
+		resp.Body.Close()
+		cancel()
+		return fmt.Errorf("%w: GET %s returned HTTP %d with Content-Type %q", ErrUnsupportedTransport, t.url, resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	t.mu.Lock()
+	t.cancel = cancel
+	t.mu.Unlock()
+
+	endpointCh := make(chan string, 1)
+	go t.readLoop(resp.Body, endpointCh)
+
+	select {
+	case <-endpointCh:
+		return nil
+	case <-t.dispatch.closed:
+		return fmt.Errorf("%w: stream closed before endpoint event: %v", ErrUnsupportedTransport, t.dispatch.closedErr())
+	case <-ctx.Done():
+		t.Close()
+		return ctx.Err()
+	}
+}
+
+// Send POSTs a request to the announced endpoint and waits for its response on the stream
+func (t *SSETransport) Send(ctx context.Context, req *Request, notify NotifyFunc) (*Response, error) {
+	ch := t.dispatch.register(req, notify)
+	defer t.dispatch.unregister(req.ID)
+
+	if err := t.post(ctx, req); err != nil {
+		return nil, err
+	}
+
+	select {
+	case msg := <-ch:
+		return msg, nil
+	case <-t.dispatch.closed:
+		return nil, t.dispatch.closedErr()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Notify POSTs a notification to the announced endpoint
+func (t *SSETransport) Notify(ctx context.Context, n *Notification) error {
+	return t.post(ctx, n)
+}
+
+// Close stops the event stream
+func (t *SSETransport) Close() error {
+	t.mu.Lock()
+	cancel := t.cancel
+	t.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	return nil
+}
+
+// readLoop consumes the event stream until it ends, announcing the POST
+// endpoint on endpointCh and routing responses to waiting requests
+func (t *SSETransport) readLoop(body io.ReadCloser, endpointCh chan<- string) {
+	defer body.Close()
+
+	reader := bufio.NewReader(body)
+	announced := false
+
+	for {
+		ev, err := readSSEEvent(reader)
+		if err != nil {
+			t.dispatch.fail(err)
+			return
+		}
+
+		switch ev.Event {
+		case "endpoint":
+			if announced {
+				continue
+			}
+			endpoint, err := t.resolve(ev.Data)
+			if err != nil {
+				continue
+			}
+			announced = true
+			// Stored here so replies to server requests that follow can be posted
+			t.mu.Lock()
+			t.endpoint = endpoint
+			t.mu.Unlock()
+			endpointCh <- endpoint
+		case "", "message":
+			if reply := t.dispatch.deliver([]byte(ev.Data)); reply != nil {
+				go t.reply(reply)
+			}
+		}
+	}
+}
+
+// reply posts the answer to a server request; a failure is left for the
+// server to time out, as the stream itself still works
+func (t *SSETransport) reply(resp *Response) {
+	ctx, cancel := context.WithTimeout(context.Background(), replyTimeout)
+	defer cancel()
+	t.post(ctx, resp)
+}
+
+// resolve turns the endpoint announced by the server into an absolute URL
+func (t *SSETransport) resolve(endpoint string) (string, error) {
+	base, err := url.Parse(t.url)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(strings.TrimSpace(endpoint))
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// post sends a JSON-RPC message to the announced endpoint
+func (t *SSETransport) post(ctx context.Context, message interface{}) error {
+	t.mu.Lock()
+	endpoint := t.endpoint
+	t.mu.Unlock()
+
+	if endpoint == "" {
+		return fmt.Errorf("SSE transport is not connected")
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	t.setHeaders(req)
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot reach MCP endpoint %s: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("MCP server returned HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	io.Copy(io.Discard, resp.Body)
+
+	return nil
+}
+
+// setHeaders adds configured headers to a request
+func (t *SSETransport) setHeaders(req *http.Request) {
+	for key, values := range t.header {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxStdioMessage bounds a single newline-delimited message from a stdio server
+const maxStdioMessage = 16 * 1024 * 1024
+
+// StdioTransport runs an MCP server as a child process and exchanges
+// newline-delimited JSON-RPC messages over its stdin and stdout
+type StdioTransport struct {
+	command string
+	args    []string
+	env     []string
+	dir     string
+
+	dispatch *dispatcher
+	stderr   *tailBuffer
+
+	mu    sync.Mutex
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+}
+
+// NewStdioTransport creates a transport that launches command with args.
+// env entries ("KEY=VALUE") are added to the current environment; dir is the
+// working directory, or the current one when empty.
+func NewStdioTransport(command string, args, env []string, dir string) *StdioTransport {
+	return &StdioTransport{
+		command:  command,
+		args:     args,
+		env:      env,
+		dir:      dir,
+		dispatch: newDispatcher(),
+		stderr:   &tailBuffer{max: 4096},
+	}
+}
+
+// Connect starts the server process
+func (t *StdioTransport) Connect(ctx context.Context) error {
+	// The process lives until Close, not until ctx is done
+	cmd := exec.Command(t.command, t.args...)
+	cmd.Env = append(os.Environ(), t.env...)
+	cmd.Dir = t.dir
+	cmd.Stderr = t.stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stdout: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", t.command, err)
+	}
+
+	t.mu.Lock()
+	t.cmd = cmd
+	t.stdin = stdin
+	t.mu.Unlock()
+
+	go t.readLoop(stdout)
+
+	return nil
+}
+
+// Send writes a request to the process and waits for its response
+func (t *StdioTransport) Send(ctx context.Context, req *Request, notify NotifyFunc) (*Response, error) {
+	ch := t.dispatch.register(req, notify)
+	defer t.dispatch.unregister(req.ID)
+
+	if err := t.write(req); err != nil {
+		return nil, err
+	}
+
+	select {
+	case msg := <-ch:
+		return msg, nil
+	case <-t.dispatch.closed:
+		return nil, t.exitErr()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Notify writes a notification to the process
+func (t *StdioTransport) Notify(ctx context.Context, n *Notification) error {
+	return t.write(n)
+}
+
+// Close closes the process's stdin and waits briefly for it to exit before killing it
+func (t *StdioTransport) Close() error {
+	t.mu.Lock()
+	cmd, stdin := t.cmd, t.stdin
+	t.cmd = nil
+	t.mu.Unlock()
+
+	if cmd == nil {
+		return nil
+	}
+
+	stdin.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		cmd.Process.Kill()
+		<-done
+	}
+
+	return nil
+}
+
+// write sends one JSON-RPC message followed by a newline
+func (t *StdioTransport) write(message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stdin == nil {
+		return fmt.Errorf("stdio transport is not connected")
+	}
+	if _, err := t.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write to %s: %w", t.command, err)
+	}
+
+	return nil
+}
+
+// readLoop reads messages from the process's stdout until it exits
+func (t *StdioTransport) readLoop(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxStdioMessage)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if reply := t.dispatch.deliver(line); reply != nil {
+			// Written aside so a server blocked on stdout cannot stall the read loop
+			go t.write(reply)
+		}
+	}
+
+	err := scanner.Err()
+	if err == nil {
+		err = io.EOF
+	}
+	t.dispatch.fail(err)
+}
+
+// exitErr describes why the process stopped answering, including its stderr tail
+func (t *StdioTransport) exitErr() error {
+	if tail := strings.TrimSpace(t.stderr.String()); tail != "" {
+		return fmt.Errorf("%s exited: %w\nstderr: %s", t.command, t.dispatch.closedErr(), tail)
+	}
+	return fmt.Errorf("%s exited: %w", t.command, t.dispatch.closedErr())
+}
+
+// tailBuffer keeps the last max bytes written to it
+type tailBuffer struct {
+	mu   sync.Mutex
+	max  int
+	data []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.data = append(b.data, p...)
+	if len(b.data) > b.max {
+		b.data = b.data[len(b.data)-b.max:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.data)
+}
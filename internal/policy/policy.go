@@ -0,0 +1,278 @@
+// Package policy classifies tool calls by how much they can change and
+// decides whether a call may run without the user's approval
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"cnb.cool/znb/learn-skills/internal/mcp"
+)
+
+// ErrDenied is returned when a tool call was not approved
+var ErrDenied = errors.New("tool call not approved")
+
+// Class is how much a tool call can change
+type Class int
+
+const (
+	// Read only inspects state
+	Read Class = iota
+	// Write changes state in a way that can be undone, e.g. starting a build
+	Write
+	// Destructive changes state irreversibly, e.g. deleting logs or merging
+	Destructive
+)
+
+// String returns the name used in configuration
+func (c Class) String() string {
+	switch c {
+	case Read:
+		return "read"
+	case Write:
+		return "write"
+	default:
+		return "destructive"
+	}
+}
+
+// ParseClass parses "read", "write" or "destructive"
+func ParseClass(s string) (Class, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "read":
+		return Read, nil
+	case "write":
+		return Write, nil
+	case "destructive":
+		return Destructive, nil
+	}
+	return Read, fmt.Errorf("unknown tool class %q (expected read, write or destructive)", s)
+}
+
+// Decision is the user's answer to an approval request
+type Decision int
+
+const (
+	// Deny refuses this call
+	Deny Decision = iota
+	// Allow runs this call
+	Allow
+	// AllowAlways runs this call and every later call of the same tool
+	AllowAlways
+)
+
+// Approval records how a call was allowed or refused
+type Approval string
+
+const (
+	// NotRequired is a read call, which always runs
+	NotRequired Approval = "not_required"
+	// Approved calls were allowed by the approver
+	Approved Approval = "approved"
+	// Remembered calls were allowed by an earlier "always" answer
+	Remembered Approval = "always"
+	// Refused calls did not run
+	Refused Approval = "denied"
+)
+
+// Request describes a tool call awaiting approval
+type Request struct {
+	Tool      string // Exposed tool name
+	Server    string // MCP server providing the tool, "" for built-in tools
+	Class     Class
+	Arguments map[string]interface{}
+}
+
+// Approver asks whether a write or destructive call may run.
+// It returns an error when it cannot ask, e.g. because ctx was cancelled.
+type Approver func(ctx context.Context, req Request) (Decision, error)
+
+// ApproveAll approves every call, for --yes
+func ApproveAll(ctx context.Context, req Request) (Decision, error) {
+	return Allow, nil
+}
+
+// Keywords in tool names, matched against the words of camelCase and snake_case names
+var (
+	destructiveWords = wordSet("delete", "remove", "rm", "destroy", "drop", "purge", "merge", "force", "reset", "revoke", "wipe", "truncate", "archive", "transfer")
+	writeWords       = wordSet("create", "update", "edit", "set", "add", "start", "stop", "trigger", "cancel", "restart", "rerun", "run", "execute", "exec", "post", "put", "patch", "write", "upload", "rename", "move", "close", "reopen", "approve", "comment", "push", "tag", "release", "deploy", "lock", "unlock", "assign", "invite")
+	readWords        = wordSet("get", "list", "search", "query", "read", "fetch", "show", "describe", "view", "download", "status", "find", "count", "stat", "inspect", "check", "load")
+)
+
+// rule assigns a class to tool names matching a glob pattern
+type rule struct {
+	pattern string
+	class   Class
+}
+
+// Policy classifies tools and remembers "always" answers for the session
+type Policy struct {
+	// ReadOnly refuses every call that is not Read, whatever the approver says
+	ReadOnly bool
+
+	rules  []rule
+	mu     sync.Mutex
+	always map[string]bool
+}
+
+// New creates a policy. overrides maps tool names or path.Match globs such as
+// "*_delete_*" to a class; they take precedence over server annotations and
+// the name heuristics. Names are matched case-insensitively.
+func New(overrides map[string]string) (*Policy, error) {
+	p := &Policy{always: make(map[string]bool)}
+	for pattern, value := range overrides {
+		class, err := ParseClass(value)
+		if err != nil {
+			return nil, fmt.Errorf("tool %q: %w", pattern, err)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+		}
+		p.rules = append(p.rules, rule{pattern: strings.ToLower(pattern), class: class})
+	}
+	// Exact names first, then longer (more specific) patterns
+	sort.Slice(p.rules, func(i, j int) bool {
+		wi, wj := hasWildcard(p.rules[i].pattern), hasWildcard(p.rules[j].pattern)
+		if wi != wj {
+			return !wi
+		}
+		return len(p.rules[i].pattern) > len(p.rules[j].pattern)
+	})
+	return p, nil
+}
+
+// Classify returns the class of a tool from the configured overrides, then
+// the server's annotations, then keywords in its name. Tools that match
+// nothing are treated as Write so they still need approval.
+func (p *Policy) Classify(tool string, hints *mcp.ToolAnnotations) Class {
+	name := strings.ToLower(tool)
+	for _, r := range p.rules {
+		if ok, _ := path.Match(r.pattern, name); ok {
+			return r.class
+		}
+	}
+
+	if hints != nil && hints.ReadOnlyHint != nil {
+		if *hints.ReadOnlyHint {
+			return Read
+		}
+		if hints.DestructiveHint != nil && !*hints.DestructiveHint {
+			return Write
+		}
+		return Destructive
+	}
+
+	return classifyName(tool)
+}
+
+// Allows reports whether calls of class can run at all under the policy
+func (p *Policy) Allows(class Class) bool {
+	return class == Read || !p.ReadOnly
+}
+
+// Authorize returns nil when a call of the given class may run: reads always
+// may, other calls need approve to allow them. approve may be nil, in which
+// case such calls are denied. In read-only mode only reads may run.
+// The returned Approval says how the decision was reached.
+func (p *Policy) Authorize(ctx context.Context, req Request, approve Approver) (Approval, error) {
+	if req.Class == Read {
+		return NotRequired, nil
+	}
+	if p.ReadOnly {
+		return Refused, fmt.Errorf("%w: %s is a %s operation and the assistant is in read-only mode", ErrDenied, req.Tool, req.Class)
+	}
+
+	p.mu.Lock()
+	always := p.always[req.Tool]
+	p.mu.Unlock()
+	if always {
+		return Remembered, nil
+	}
+
+	if approve == nil {
+		return Refused, fmt.Errorf("%w: %s is a %s operation and needs approval (run with --yes to allow it)", ErrDenied, req.Tool, req.Class)
+	}
+
+	decision, err := approve(ctx, req)
+	if err != nil {
+		return Refused, err
+	}
+	switch decision {
+	case AllowAlways:
+		p.mu.Lock()
+		p.always[req.Tool] = true
+		p.mu.Unlock()
+		return Approved, nil
+	case Allow:
+		return Approved, nil
+	}
+	return Refused, fmt.Errorf("%w: the user declined to run %s", ErrDenied, req.Tool)
+}
+
+// classifyName guesses the class of a tool from the words in its name
+func classifyName(tool string) Class {
+	words := splitWords(tool)
+	for _, w := range words {
+		if destructiveWords[w] {
+			return Destructive
+		}
+	}
+	for _, w := range words {
+		if writeWords[w] {
+			return Write
+		}
+	}
+	for _, w := range words {
+		if readWords[w] {
+			return Read
+		}
+	}
+	return Write
+}
+
+// splitWords splits snake_case, kebab-case and camelCase names into lower case words
+func splitWords(name string) []string {
+	var words []string
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, strings.ToLower(string(current)))
+			current = current[:0]
+		}
+	}
+
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) ||
+			(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))):
+			flush()
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
+}
+
+// hasWildcard reports whether pattern contains glob metacharacters
+func hasWildcard(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// wordSet builds a lookup set from words
+func wordSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
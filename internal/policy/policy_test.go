@@ -0,0 +1,116 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cnb.cool/znb/learn-skills/internal/mcp"
+)
+
+func TestClassifyByName(t *testing.T) {
+	p, err := New(nil)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	tests := map[string]Class{
+		"cnb_get_repository":         Read,
+		"cnb_getBuildLogs":           Read,
+		"cnb_buildRunnerDownloadLog": Read,
+		"list_mcp_resources":         Read,
+		"load_skill":                 Read,
+		"cnb_startBuild":             Write,
+		"cnb_create_pull_comment":    Write,
+		"execute_bash":               Write,
+		"cnb_merge_pull":             Destructive,
+		"cnb_buildLogsDelete":        Destructive,
+		"cnb_delete_workspace":       Destructive,
+		"lint-bot__frobnicate":       Write,
+	}
+	for tool, want := range tests {
+		if got := p.Classify(tool, nil); got != want {
+			t.Errorf("Classify(%q) = %s, want %s", tool, got, want)
+		}
+	}
+}
+
+func TestClassifyPrecedence(t *testing.T) {
+	p, err := New(map[string]string{
+		"cnb_startbuild": "read",
+		"release_*":      "destructive",
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	yes, no := true, false
+	readOnly := &mcp.ToolAnnotations{ReadOnlyHint: &yes}
+	additive := &mcp.ToolAnnotations{ReadOnlyHint: &no, DestructiveHint: &no}
+
+	if got := p.Classify("cnb_startBuild", nil); got != Read {
+		t.Errorf("Expected the exact override to win, got %s", got)
+	}
+	if got := p.Classify("release_get_notes", readOnly); got != Destructive {
+		t.Errorf("Expected the pattern override to win over annotations, got %s", got)
+	}
+	if got := p.Classify("cnb_delete_cache", readOnly); got != Read {
+		t.Errorf("Expected readOnlyHint to win over the name, got %s", got)
+	}
+	if got := p.Classify("cnb_get_thing", additive); got != Write {
+		t.Errorf("Expected a non-destructive write hint to give write, got %s", got)
+	}
+
+	if _, err := New(map[string]string{"x": "sometimes"}); err == nil {
+		t.Error("Expected New() to reject an unknown class")
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	p, _ := New(nil)
+	ctx := context.Background()
+	write := Request{Tool: "cnb_startBuild", Class: Write}
+
+	if _, err := p.Authorize(ctx, Request{Tool: "cnb_get_repository", Class: Read}, nil); err != nil {
+		t.Errorf("Expected reads to need no approval, got %v", err)
+	}
+	if _, err := p.Authorize(ctx, write, nil); !errors.Is(err, ErrDenied) {
+		t.Errorf("Expected a write without approver to be denied, got %v", err)
+	}
+
+	asked := 0
+	answer := Deny
+	approve := func(ctx context.Context, req Request) (Decision, error) {
+		asked++
+		return answer, nil
+	}
+	if _, err := p.Authorize(ctx, write, approve); !errors.Is(err, ErrDenied) {
+		t.Errorf("Expected a declined call to be denied, got %v", err)
+	}
+
+	answer = AllowAlways
+	if _, err := p.Authorize(ctx, write, approve); err != nil {
+		t.Errorf("Expected an approved call to run, got %v", err)
+	}
+	approval, err := p.Authorize(ctx, write, approve)
+	if err != nil || approval != Remembered || asked != 2 {
+		t.Errorf("Expected \"always\" to skip the prompt, got %s err=%v asked=%d", approval, err, asked)
+	}
+}
+
+func TestAuthorizeReadOnly(t *testing.T) {
+	p, _ := New(nil)
+	p.ReadOnly = true
+	ctx := context.Background()
+
+	if !p.Allows(Read) || p.Allows(Write) || p.Allows(Destructive) {
+		t.Error("Expected read-only mode to allow reads only")
+	}
+	if _, err := p.Authorize(ctx, Request{Tool: "cnb_get_repository", Class: Read}, nil); err != nil {
+		t.Errorf("Expected reads to run in read-only mode, got %v", err)
+	}
+	_, err := p.Authorize(ctx, Request{Tool: "cnb_merge_pull", Class: Destructive}, ApproveAll)
+	if !errors.Is(err, ErrDenied) {
+		t.Errorf("Expected read-only mode to override approval, got %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package redact
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// heldWords is how many trailing words a Stream keeps back. A secret pattern
+// spans at most three words, as in "Authorization: token <value>", so text
+// before them can no longer become part of a new match.
+const heldWords = 3
+
+// Stream redacts text that arrives in pieces, such as a streamed LLM reply.
+// Masking each piece on its own misses a secret split across pieces, so the
+// end of the text is held back until it can no longer be cut through a
+// secret, and only text that is complete is masked and passed on.
+type Stream struct {
+	r   *Redactor
+	out func(string) error
+	buf string
+}
+
+// NewStream returns a stream that passes redacted text to out.
+// r may be nil, in which case only the well-known formats are masked.
+func NewStream(r *Redactor, out func(string) error) *Stream {
+	return &Stream{r: r, out: out}
+}
+
+// Write adds a piece of text and passes on what is safe to redact so far
+func (s *Stream) Write(chunk string) error {
+	s.buf += chunk
+	cut := s.safeCut()
+	if cut == 0 {
+		return nil
+	}
+	text := s.buf[:cut]
+	s.buf = s.buf[cut:]
+	return s.out(s.r.String(text))
+}
+
+// Flush redacts and passes on the text held back
+func (s *Stream) Flush() error {
+	if s.buf == "" {
+		return nil
+	}
+	text := s.buf
+	s.buf = ""
+	return s.out(s.r.String(text))
+}
+
+// safeCut returns the length of the longest prefix of the buffer that ends
+// at a word boundary, is followed by at least heldWords words, and does not
+// cut through a secret or an unfinished private key block; 0 if there is none.
+// Words are separated by spaces and by punctuation such as "，" or "。", and
+// a run of CJK text holds one word per rune, so replies without spaces
+// still stream. A run that contains ASCII is never split outside a
+// separator, because secrets and the values of "password=..." are ASCII-led.
+func (s *Stream) safeCut() int {
+	limit := len(s.buf)
+	if i := strings.LastIndex(s.buf, "-----BEGIN"); i >= 0 && !strings.Contains(s.buf[i:], "-----END") {
+		limit = i
+	}
+	spans := s.r.matches(s.buf)
+
+	var boundaries, wordStarts []int
+	prev, runHasASCII := rune(-1), false
+	for i, c := range s.buf {
+		sep := isSeparator(c)
+		if i > 0 {
+			switch {
+			case sep || isSeparator(prev):
+				boundaries = append(boundaries, i)
+			case prev >= utf8.RuneSelf && c >= utf8.RuneSelf && !runHasASCII:
+				boundaries = append(boundaries, i)
+			}
+		}
+		if !sep && (i == 0 || len(boundaries) > 0 && boundaries[len(boundaries)-1] == i) {
+			wordStarts = append(wordStarts, i)
+		}
+		if sep {
+			runHasASCII = false
+		} else if c < utf8.RuneSelf {
+			runHasASCII = true
+		}
+		prev = c
+	}
+
+	// Walk back from the last boundary until enough words follow it
+	for b := len(boundaries) - 1; b >= 0; b-- {
+		i := boundaries[b]
+		held := len(wordStarts) - sort.SearchInts(wordStarts, i)
+		if held < heldWords || i > limit || inSpan(spans, i) {
+			continue
+		}
+		return i
+	}
+	return 0
+}
+
+// matches returns the spans of the secrets and secret patterns in s
+func (r *Redactor) matches(s string) [][]int {
+	var spans [][]int
+	if r != nil {
+		for _, secret := range r.secrets {
+			for start := 0; ; {
+				i := strings.Index(s[start:], secret)
+				if i < 0 {
+					break
+				}
+				spans = append(spans, []int{start + i, start + i + len(secret)})
+				start += i + 1
+			}
+		}
+	}
+	for _, p := range patterns {
+		spans = append(spans, p.re.FindAllStringIndex(s, -1)...)
+	}
+	return spans
+}
+
+// inSpan reports whether i falls strictly inside one of spans
+func inSpan(spans [][]int, i int) bool {
+	for _, span := range spans {
+		if span[0] < i && i < span[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// isSeparator reports whether c separates words: any space, or punctuation
+// outside ASCII, where ASCII punctuation such as "-" or "=" belongs to tokens
+func isSeparator(c rune) bool {
+	return unicode.IsSpace(c) || c >= utf8.RuneSelf && unicode.IsPunct(c)
+}
@@ -0,0 +1,226 @@
+// Package sandbox runs execute_bash commands under a configured profile:
+// allow/deny patterns, a fixed working directory with optional path
+// restriction, a scrubbed environment, rlimits and, on Linux, optional
+// namespace isolation
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"cnb.cool/znb/learn-skills/internal/config"
+)
+
+// ErrBlocked is returned when a command is refused by the profile
+var ErrBlocked = errors.New("command blocked by sandbox")
+
+// baseEnv are the variables every command inherits from the environment
+var baseEnv = []string{"PATH", "HOME", "USER", "LOGNAME", "SHELL", "LANG", "LC_ALL", "LC_CTYPE", "TERM", "TMPDIR", "TZ"}
+
+// Sandbox runs shell commands confined by a profile
+type Sandbox struct {
+	name    string
+	profile config.SandboxProfile
+	allow   []*regexp.Regexp
+	deny    []*regexp.Regexp
+	warning string
+}
+
+// New compiles a profile. name only appears in messages. Outside Linux a
+// profile asking for isolation runs without it, as Warning then explains.
+func New(name string, profile config.SandboxProfile) (*Sandbox, error) {
+	var warning string
+	if profile.Isolate && !isolationSupported {
+		profile.Isolate, profile.NoNetwork = false, false
+		warning = fmt.Sprintf("sandbox profile %q: namespace isolation is only supported on Linux; commands run without it", name)
+	}
+	if profile.Dir != "" {
+		info, err := os.Stat(profile.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("sandbox profile %q: %w", name, err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("sandbox profile %q: %s is not a directory", name, profile.Dir)
+		}
+	}
+
+	s := &Sandbox{name: name, profile: profile, warning: warning}
+	var err error
+	if s.allow, err = compilePatterns(profile.Allow); err != nil {
+		return nil, fmt.Errorf("sandbox profile %q: allow: %w", name, err)
+	}
+	if s.deny, err = compilePatterns(profile.Deny); err != nil {
+		return nil, fmt.Errorf("sandbox profile %q: deny: %w", name, err)
+	}
+	return s, nil
+}
+
+// Name returns the profile name
+func (s *Sandbox) Name() string {
+	return s.name
+}
+
+// Warning returns what the profile could not enforce on this platform, "" if nothing
+func (s *Sandbox) Warning() string {
+	return s.warning
+}
+
+// PassesEnv reports whether the profile passes the variable name through
+func (s *Sandbox) PassesEnv(name string) bool {
+	return slices.Contains(s.profile.Env, name)
+}
+
+// Check returns an error wrapping ErrBlocked that explains why command may not run
+func (s *Sandbox) Check(command string) error {
+	for _, re := range s.deny {
+		if re.MatchString(command) {
+			return fmt.Errorf("%w: it matches the deny pattern %q of sandbox profile %q", ErrBlocked, re.String(), s.name)
+		}
+	}
+	if s.profile.RestrictPaths {
+		if path := escapingPath(command); path != "" {
+			return fmt.Errorf("%w: %q is outside the working directory, and sandbox profile %q only allows relative paths below it", ErrBlocked, path, s.name)
+		}
+	}
+	if len(s.allow) == 0 {
+		return nil
+	}
+	for _, re := range s.allow {
+		if re.MatchString(command) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: it matches none of the allowed patterns of sandbox profile %q (%s)", ErrBlocked, s.name, strings.Join(s.profile.Allow, ", "))
+}
+
+// escapingPath returns the first word of command, or value of a --flag=value
+// word, that names a path outside the working directory: an absolute path, a
+// home directory or one that climbs out with "..". It returns "" if there is
+// none. The check is lexical, so it only holds together with an allow list
+// that rules out expansions, command substitution and cd, as strict does.
+func escapingPath(command string) string {
+	words := strings.FieldsFunc(command, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(";&|<>()", r)
+	})
+	for _, word := range words {
+		word = strings.Trim(word, `"'`)
+		if i := strings.IndexByte(word, '='); i >= 0 {
+			word = strings.Trim(word[i+1:], `"'`)
+		}
+		if strings.HasPrefix(word, "/") || strings.HasPrefix(word, "~") || slices.Contains(strings.Split(word, "/"), "..") {
+			return word
+		}
+	}
+	return ""
+}
+
+// Run executes command with bash inside the sandbox and returns its combined
+// output, trimmed. env adds variables on top of the scrubbed environment.
+// The command is killed when ctx is cancelled.
+func (s *Sandbox) Run(ctx context.Context, command string, env []string) (string, error) {
+	if err := s.Check(command); err != nil {
+		return "", err
+	}
+
+	cmd := exec.CommandContext(ctx, "bash", "-c", s.limits()+command)
+	cmd.Dir = s.profile.Dir
+	cmd.Env = append(s.environ(), env...)
+	cmd.SysProcAttr = sysProcAttr(s.profile)
+	// Don't wait forever on children that inherited the output pipes
+	cmd.WaitDelay = 2 * time.Second
+
+	out := &limitedBuffer{limit: s.profile.MaxOutputBytes}
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	err := cmd.Run()
+	output := out.String()
+	if ctx.Err() != nil {
+		return output, ctx.Err()
+	}
+	if err != nil {
+		return output, fmt.Errorf("command failed: %w\nOutput: %s", err, output)
+	}
+
+	return strings.TrimSpace(output), nil
+}
+
+// limits returns the ulimit prefix enforcing the profile's resource limits.
+// Without -S or -H bash lowers both limits, so the command cannot raise them again.
+func (s *Sandbox) limits() string {
+	var sb strings.Builder
+	if s.profile.CPUSeconds > 0 {
+		fmt.Fprintf(&sb, "ulimit -t %d || exit 126; ", s.profile.CPUSeconds)
+	}
+	if s.profile.MemoryMB > 0 {
+		fmt.Fprintf(&sb, "ulimit -v %d || exit 126; ", s.profile.MemoryMB*1024)
+	}
+	return sb.String()
+}
+
+// environ returns the scrubbed environment: the base variables plus those the
+// profile passes through, taken from the current process
+func (s *Sandbox) environ() []string {
+	var env []string
+	for _, name := range append(baseEnv, s.profile.Env...) {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+// compilePatterns compiles regular expressions
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// limitedBuffer keeps at most limit bytes of output (all of it when limit is 0)
+// and notes how much was dropped
+type limitedBuffer struct {
+	buf     bytes.Buffer
+	limit   int
+	dropped int
+}
+
+// Write implements io.Writer; it never fails so the command is not disturbed
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if b.limit > 0 {
+		room := b.limit - b.buf.Len()
+		if room < len(p) {
+			if room < 0 {
+				room = 0
+			}
+			b.dropped += len(p) - room
+			p = p[:room]
+		}
+	}
+	b.buf.Write(p)
+	return n, nil
+}
+
+// String returns the kept output
+func (b *limitedBuffer) String() string {
+	if b.dropped == 0 {
+		return b.buf.String()
+	}
+	return fmt.Sprintf("%s\n…(output truncated, %d more bytes dropped by the sandbox)", b.buf.String(), b.dropped)
+}
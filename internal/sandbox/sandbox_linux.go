@@ -0,0 +1,32 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"os"
+	"syscall"
+
+	"cnb.cool/znb/learn-skills/internal/config"
+)
+
+// isolationSupported reports whether profiles may set Isolate
+const isolationSupported = true
+
+// sysProcAttr puts the command in fresh namespaces when the profile asks for it.
+// A user namespace maps the current user, so no privileges are needed.
+func sysProcAttr(profile config.SandboxProfile) *syscall.SysProcAttr {
+	if !profile.Isolate {
+		return nil
+	}
+
+	flags := syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	if profile.NoNetwork {
+		flags |= syscall.CLONE_NEWNET
+	}
+	return &syscall.SysProcAttr{
+		Cloneflags:  uintptr(flags),
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}},
+		Pdeathsig:   syscall.SIGKILL,
+	}
+}
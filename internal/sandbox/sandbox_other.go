@@ -0,0 +1,17 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"syscall"
+
+	"cnb.cool/znb/learn-skills/internal/config"
+)
+
+// isolationSupported reports whether profiles may set Isolate
+const isolationSupported = false
+
+// sysProcAttr has nothing to add outside Linux
+func sysProcAttr(profile config.SandboxProfile) *syscall.SysProcAttr {
+	return nil
+}
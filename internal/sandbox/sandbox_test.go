@@ -0,0 +1,117 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cnb.cool/znb/learn-skills/internal/config"
+)
+
+func TestCheck(t *testing.T) {
+	s, err := New("test", config.SandboxProfile{
+		Allow: []string{`^(ls|cat|rm)\b`},
+		Deny:  []string{`\brm\s+-rf\b`},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	tests := map[string]bool{
+		"ls -la":          true,
+		"cat README.md":   true,
+		"rm build.log":    true,
+		"rm -rf /":        false,
+		"curl example.io": false,
+	}
+	for command, allowed := range tests {
+		err := s.Check(command)
+		if allowed && err != nil {
+			t.Errorf("Check(%q) = %v, want allowed", command, err)
+		}
+		if !allowed && !errors.Is(err, ErrBlocked) {
+			t.Errorf("Check(%q) = %v, want ErrBlocked", command, err)
+		}
+	}
+
+	if _, err := New("bad", config.SandboxProfile{Deny: []string{"("}}); err == nil {
+		t.Error("Expected New() to reject an invalid pattern")
+	}
+}
+
+func TestCheckRestrictPaths(t *testing.T) {
+	s, err := New("test", config.SandboxProfile{RestrictPaths: true})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	tests := map[string]bool{
+		"cat README.md":                    true,
+		"ls -la docs/plans":                true,
+		"grep -rn token ./internal":        true,
+		"du -sh dist/..hidden":             true,
+		"ls ~":                             false,
+		"cat /etc/hostname":                false,
+		"cat ~/.cnb-assistant/config.yaml": false,
+		"cat ../secrets.env":               false,
+		"head -n 5 docs/../../x":           false,
+		"grep --file=/etc/passwd x":        false,
+		`cat "/etc/hostname"`:              false,
+		"ls;cat /etc/hostname":             false,
+	}
+	for command, allowed := range tests {
+		err := s.Check(command)
+		if allowed && err != nil {
+			t.Errorf("Check(%q) = %v, want allowed", command, err)
+		}
+		if !allowed && !errors.Is(err, ErrBlocked) {
+			t.Errorf("Check(%q) = %v, want ErrBlocked", command, err)
+		}
+	}
+}
+
+func TestRunConfinesCommand(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SANDBOX_SECRET", "hunter2")
+	t.Setenv("SANDBOX_PASSED", "visible")
+
+	s, err := New("test", config.SandboxProfile{
+		Dir:            dir,
+		Env:            []string{"SANDBOX_PASSED"},
+		CPUSeconds:     5,
+		MaxOutputBytes: 64,
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx := context.Background()
+
+	out, err := s.Run(ctx, `pwd; echo "[$SANDBOX_SECRET][$SANDBOX_PASSED][$EXTRA]"; ulimit -t`, []string{"EXTRA=added"})
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	want := strings.Join([]string{resolve(t, dir), "[][visible][added]", "5"}, "\n")
+	if out != want {
+		t.Errorf("Run() = %q, want %q", out, want)
+	}
+
+	out, err = s.Run(ctx, "head -c 1000 /dev/zero | tr '\\0' x", nil)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if !strings.HasPrefix(out, strings.Repeat("x", 64)+"\n") || !strings.Contains(out, "936 more bytes") {
+		t.Errorf("Expected output to be cut at 64 bytes, got %q", out)
+	}
+}
+
+// resolve returns dir with symlinks resolved, as printed by pwd
+func resolve(t *testing.T, dir string) string {
+	t.Helper()
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resolved
+}
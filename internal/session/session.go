@@ -0,0 +1,209 @@
+// Package session persists conversations so they can be listed and resumed
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"cnb.cool/znb/learn-skills/internal/llm"
+)
+
+// ErrNotFound is returned when no stored session matches an ID
+var ErrNotFound = errors.New("session not found")
+
+// Latest is accepted by Load in place of an ID to pick the most recently updated session
+const Latest = "last"
+
+// Session is a persisted conversation
+type Session struct {
+	ID        string        `json:"id"`
+	Title     string        `json:"title"` // First user message, shortened
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	Messages  []llm.Message `json:"messages"` // Conversation without the system prompt
+	MCPCalls  []MCPCall     `json:"mcp_calls,omitempty"`
+	Skills    []string      `json:"skills,omitempty"` // Skills loaded into the conversation, in load order
+}
+
+// MCPCall records one MCP tool call made during the session
+type MCPCall struct {
+	Server    string                 `json:"server"`
+	ToolName  string                 `json:"tool_name"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	StartTime time.Time              `json:"start_time"`
+	EndTime   time.Time              `json:"end_time"`
+}
+
+// Store keeps sessions as one JSON file per session in a directory
+type Store struct {
+	dir string
+}
+
+// NewStore creates a store rooted at dir; the directory is created on first save
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// DefaultDir returns ~/.cnb-assistant/sessions
+func DefaultDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate home directory: %w", err)
+	}
+	return filepath.Join(home, ".cnb-assistant", "sessions"), nil
+}
+
+// Dir returns the directory holding the session files
+func (st *Store) Dir() string {
+	return st.dir
+}
+
+// New creates an empty session with a fresh ID. It is not written until Save.
+func (st *Store) New() *Session {
+	now := time.Now()
+	return &Session{
+		ID:        newID(now),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// Save writes the session, replacing any previous version atomically
+func (st *Store) Save(s *Session) error {
+	if err := os.MkdirAll(st.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	s.UpdatedAt = time.Now()
+	if s.Title == "" {
+		s.Title = titleFor(s.Messages)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(st.dir, s.ID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), st.path(s.ID)); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	return nil
+}
+
+// Load reads a session by ID. A unique ID prefix or Latest is accepted too.
+func (st *Store) Load(id string) (*Session, error) {
+	sessions, err := st.List()
+	if err != nil {
+		return nil, err
+	}
+
+	if id == Latest {
+		if len(sessions) == 0 {
+			return nil, ErrNotFound
+		}
+		return sessions[0], nil
+	}
+
+	var matches []*Session
+	for _, s := range sessions {
+		if s.ID == id {
+			return s, nil
+		}
+		if strings.HasPrefix(s.ID, id) {
+			matches = append(matches, s)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("session ID %q is ambiguous (%d matches)", id, len(matches))
+	}
+}
+
+// List returns all stored sessions, most recently updated first.
+// Files that cannot be decoded are skipped.
+func (st *Store) List() ([]*Session, error) {
+	entries, err := os.ReadDir(st.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session directory: %w", err)
+	}
+
+	var sessions []*Session
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(st.dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var s Session
+		if err := json.Unmarshal(data, &s); err != nil || s.ID == "" {
+			continue
+		}
+		sessions = append(sessions, &s)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
+	})
+	return sessions, nil
+}
+
+// path returns the file holding the session with the given ID
+func (st *Store) path(id string) string {
+	return filepath.Join(st.dir, id+".json")
+}
+
+// newID builds a sortable, human friendly ID such as 20250101-150405-a1b2c3
+func newID(now time.Time) string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return now.Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// titleFor derives a title from the first user message
+func titleFor(messages []llm.Message) string {
+	const maxRunes = 60
+
+	for _, m := range messages {
+		if m.Role != "user" {
+			continue
+		}
+		title := strings.Join(strings.Fields(m.Content), " ")
+		if utf8.RuneCountInString(title) > maxRunes {
+			title = string([]rune(title)[:maxRunes]) + "…"
+		}
+		return title
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+package session
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"cnb.cool/znb/learn-skills/internal/llm"
+)
+
+func TestStoreSaveLoadList(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	first := store.New()
+	first.Messages = []llm.Message{
+		{Role: "user", Content: "List my repositories"},
+		{Role: "assistant", Content: "You have 3 repositories."},
+	}
+	first.MCPCalls = []MCPCall{{
+		Server:    "cnb",
+		ToolName:  "list_repositories",
+		Arguments: map[string]interface{}{"page": float64(1)},
+		StartTime: time.Now(),
+		EndTime:   time.Now(),
+	}}
+	if err := store.Save(first); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	second := store.New()
+	second.ID = first.ID + "b"
+	second.Messages = []llm.Message{{Role: "user", Content: "Show build #123"}}
+	if err := store.Save(second); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	sessions, err := store.List()
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].ID != second.ID {
+		t.Fatalf("Expected 2 sessions with the latest first, got %+v", sessions)
+	}
+
+	loaded, err := store.Load(first.ID[:10])
+	if err == nil {
+		t.Fatalf("Expected ambiguous prefix error, got session %s", loaded.ID)
+	}
+
+	loaded, err = store.Load(first.ID)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if loaded.Title != "List my repositories" || len(loaded.Messages) != 2 {
+		t.Errorf("Unexpected session: title=%q messages=%d", loaded.Title, len(loaded.Messages))
+	}
+	if len(loaded.MCPCalls) != 1 || loaded.MCPCalls[0].ToolName != "list_repositories" {
+		t.Errorf("Unexpected MCP calls: %+v", loaded.MCPCalls)
+	}
+
+	latest, err := store.Load(Latest)
+	if err != nil || latest.ID != second.ID {
+		t.Errorf("Expected latest session %s, got %v (%v)", second.ID, latest, err)
+	}
+
+	if _, err := store.Load("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
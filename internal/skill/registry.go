@@ -0,0 +1,128 @@
+package skill
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SystemDir holds skills installed for every user
+const SystemDir = "/etc/cnb-assistant/skills"
+
+// Registry holds the skills found in a list of directories
+type Registry struct {
+	skills map[string]*Skill
+	names  []string // Sorted
+}
+
+// DefaultDirs returns the directories searched when none are configured, in
+// order of precedence: skills/ in the working directory, skills/ next to the
+// executable, ~/.cnb-assistant/skills and the system-wide directory
+func DefaultDirs() []string {
+	dirs := []string{"skills"}
+	if exe, err := os.Executable(); err == nil {
+		dirs = append(dirs, filepath.Join(filepath.Dir(exe), "skills"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".cnb-assistant", "skills"))
+	}
+	return append(dirs, SystemDir)
+}
+
+// Load reads the skills found by Discover. When two directories have a skill
+// of the same name the one listed first wins, so a repository can override a
+// skill installed for the user. Skills that cannot be read are left out and
+// reported together in the error, alongside the registry of the others.
+func Load(dirs []string) (*Registry, error) {
+	r := &Registry{skills: map[string]*Skill{}}
+	skillDirs, err := Discover(dirs)
+	errs := []error{err}
+
+	for _, dir := range skillDirs {
+		s, err := Read(dir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, ok := r.skills[s.Name]; ok {
+			continue
+		}
+		r.skills[s.Name] = s
+		r.names = append(r.names, s.Name)
+	}
+
+	sort.Strings(r.names)
+	return r, errors.Join(errs...)
+}
+
+// Discover returns the subdirectories of dirs that hold a SKILL.md, in the
+// order of dirs. Missing directories are skipped; directories that cannot be
+// read are reported in the error.
+func Discover(dirs []string) ([]string, error) {
+	var skillDirs []string
+	seen := map[string]bool{}
+	var errs []error
+
+	for _, dir := range dirs {
+		dir = expandHome(dir)
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read skill directory: %w", err))
+			continue
+		}
+
+		for _, entry := range entries {
+			skillDir := filepath.Join(dir, entry.Name())
+			if info, err := os.Stat(filepath.Join(skillDir, FileName)); err == nil && !info.IsDir() {
+				skillDirs = append(skillDirs, skillDir)
+			}
+		}
+	}
+	return skillDirs, errors.Join(errs...)
+}
+
+// Get returns the skill with the given name
+func (r *Registry) Get(name string) (*Skill, bool) {
+	s, ok := r.skills[name]
+	return s, ok
+}
+
+// All returns the skills sorted by name
+func (r *Registry) All() []*Skill {
+	skills := make([]*Skill, 0, len(r.names))
+	for _, name := range r.names {
+		skills = append(skills, r.skills[name])
+	}
+	return skills
+}
+
+// Len returns the number of skills
+func (r *Registry) Len() int {
+	return len(r.names)
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
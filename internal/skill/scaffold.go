@@ -0,0 +1,64 @@
+package skill
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// template is the SKILL.md written for a new skill
+const template = `---
+name: %s
+description: Describe what the skill does and when the assistant should load it
+version: 0.1.0
+# env: [CNB_TOKEN]
+# allowed-tools: [cnb/*]
+# scripts: [scripts/example.sh]
+# references: [references/guide.md]
+---
+
+# %s
+
+## When to use
+
+Describe the requests this skill handles.
+
+## Steps
+
+1. List the tools to call and in which order.
+2. Describe how to present the results.
+
+## Errors
+
+Describe what to tell the user when a tool fails.
+`
+
+// Scaffold creates a new skill called name in parent/name, with a SKILL.md
+// to fill in and empty scripts and references directories. It refuses to
+// touch an existing directory.
+func Scaffold(parent, name string) (string, error) {
+	if !validName.MatchString(name) {
+		return "", fmt.Errorf("invalid name %q (use lowercase letters, digits and hyphens)", name)
+	}
+
+	dir := filepath.Join(expandHome(parent), name)
+	if _, err := os.Stat(dir); err == nil {
+		return "", fmt.Errorf("%s already exists", dir)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	for _, sub := range []string{"scripts", "references"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return "", fmt.Errorf("failed to create skill directory: %w", err)
+		}
+	}
+	title := strings.ReplaceAll(name, "-", " ")
+	content := fmt.Sprintf(template, name, strings.ToUpper(title[:1])+title[1:])
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte(content), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", FileName, err)
+	}
+	return dir, nil
+}
@@ -0,0 +1,242 @@
+// Package skill discovers the skills the assistant can use. A skill is a
+// directory holding a SKILL.md file: YAML frontmatter with the skill's
+// manifest, followed by the instructions for the model.
+package skill
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// FileName is the file that makes a directory a skill
+const FileName = "SKILL.md"
+
+// Patterns the frontmatter fields are checked against
+var (
+	// validName keeps skill names usable as identifiers and directory names
+	validName    = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+	validVersion = regexp.MustCompile(`^v?\d+(\.\d+){0,2}([-+][0-9A-Za-z.-]+)?$`)
+	validEnv     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Skill is a skill read from disk: the manifest from its frontmatter and
+// the instructions that follow it
+type Skill struct {
+	Name         string
+	Description  string
+	Version      string
+	License      string
+	Env          []string          // Environment variables the skill needs
+	AllowedTools []string          // Tool names or glob patterns the skill may call; "cnb/*" matches every tool of server cnb
+	Scripts      []string          // Helper scripts, relative to Dir
+	References   []string          // Reference documents, relative to Dir
+	Metadata     map[string]string // Free-form extra fields
+	Dir          string            // Directory holding SKILL.md
+	Body         string            // The instructions after the frontmatter
+}
+
+// Path returns the SKILL.md file of the skill
+func (s *Skill) Path() string {
+	return filepath.Join(s.Dir, FileName)
+}
+
+// AllowsTool reports whether the skill may call a tool known by any of
+// names, e.g. its exposed name and "server/tool" for an MCP tool. Patterns
+// match case-insensitively; a skill without allowed-tools may call any tool.
+func (s *Skill) AllowsTool(names ...string) bool {
+	if len(s.AllowedTools) == 0 {
+		return true
+	}
+	for _, pattern := range s.AllowedTools {
+		for _, name := range names {
+			if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(name)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MissingEnv returns the variables of Env that are not set
+func (s *Skill) MissingEnv() []string {
+	var missing []string
+	for _, name := range s.Env {
+		if _, ok := os.LookupEnv(name); !ok {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// manifest is the YAML frontmatter of SKILL.md
+type manifest struct {
+	Name         string            `yaml:"name"`
+	Description  string            `yaml:"description"`
+	Version      string            `yaml:"version"`
+	License      string            `yaml:"license"`
+	Env          stringList        `yaml:"env"`
+	AllowedTools stringList        `yaml:"allowed-tools"`
+	Scripts      stringList        `yaml:"scripts"`
+	References   stringList        `yaml:"references"`
+	Metadata     map[string]string `yaml:"metadata"`
+}
+
+// stringList accepts a YAML sequence or a single string of items separated
+// by commas or spaces, as in "allowed-tools: cnb_* execute_bash"
+type stringList []string
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (l *stringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = strings.FieldsFunc(node.Value, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+		return nil
+	}
+	var items []string
+	if err := node.Decode(&items); err != nil {
+		return err
+	}
+	*l = items
+	return nil
+}
+
+// Read loads the skill in dir
+func Read(dir string) (*Skill, error) {
+	path := filepath.Join(dir, FileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read skill: %w", err)
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	s.Dir = dir
+	return s, nil
+}
+
+// Parse parses and validates the content of a SKILL.md file
+func Parse(data []byte) (*Skill, error) {
+	header, body, err := splitFrontmatter(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var m manifest
+	dec := yaml.NewDecoder(bytes.NewReader(header))
+	dec.KnownFields(true)
+	if err := dec.Decode(&m); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid frontmatter: %w", err)
+	}
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+
+	return &Skill{
+		Name:         m.Name,
+		Description:  strings.TrimSpace(m.Description),
+		Version:      m.Version,
+		License:      m.License,
+		Env:          m.Env,
+		AllowedTools: m.AllowedTools,
+		Scripts:      m.Scripts,
+		References:   m.References,
+		Metadata:     m.Metadata,
+		Body:         strings.TrimSpace(string(body)),
+	}, nil
+}
+
+// validate checks the fields of the manifest
+func (m *manifest) validate() error {
+	switch {
+	case m.Name == "":
+		return fmt.Errorf("frontmatter has no name")
+	case !validName.MatchString(m.Name):
+		return fmt.Errorf("invalid name %q (use lowercase letters, digits and hyphens)", m.Name)
+	case strings.TrimSpace(m.Description) == "":
+		return fmt.Errorf("frontmatter has no description")
+	case m.Version != "" && !validVersion.MatchString(m.Version):
+		return fmt.Errorf("invalid version %q (expected a version such as 1.2.0)", m.Version)
+	}
+
+	for _, name := range m.Env {
+		if !validEnv.MatchString(name) {
+			return fmt.Errorf("invalid env variable name %q", name)
+		}
+	}
+	for _, pattern := range m.AllowedTools {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid allowed-tools pattern %q", pattern)
+		}
+	}
+	for _, files := range []struct {
+		field string
+		paths []string
+	}{{"scripts", m.Scripts}, {"references", m.References}} {
+		for _, p := range files.paths {
+			if !filepath.IsLocal(p) {
+				return fmt.Errorf("%s entry %q must be a path inside the skill directory", files.field, p)
+			}
+		}
+	}
+	return nil
+}
+
+// splitFrontmatter separates the YAML between the leading "---" lines from the rest
+func splitFrontmatter(data []byte) (header, body []byte, err error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	if !bytes.HasPrefix(data, []byte("---\n")) {
+		return nil, nil, fmt.Errorf("missing frontmatter (the file must start with a --- line)")
+	}
+	rest := data[len("---\n"):]
+
+	end := bytes.Index(rest, []byte("\n---\n"))
+	switch {
+	case bytes.HasPrefix(rest, []byte("---\n")):
+		return nil, rest[len("---\n"):], nil
+	case end >= 0:
+		return rest[:end+1], rest[end+len("\n---\n"):], nil
+	case bytes.HasSuffix(rest, []byte("\n---")):
+		return rest[:len(rest)-len("---")], nil, nil
+	}
+	return nil, nil, fmt.Errorf("unterminated frontmatter (no closing --- line)")
+}
+
+// CheckFiles reports declared scripts that are missing or not executable and
+// references that are missing, all in one error
+func (s *Skill) CheckFiles() error {
+	var errs []error
+	for _, script := range s.Scripts {
+		info, err := os.Stat(filepath.Join(s.Dir, script))
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("script %s: %w", script, err))
+		case !info.Mode().IsRegular():
+			errs = append(errs, fmt.Errorf("script %s is not a regular file", script))
+		case info.Mode().Perm()&0o111 == 0:
+			errs = append(errs, fmt.Errorf("script %s is not executable", script))
+		}
+	}
+	for _, ref := range s.References {
+		if _, err := os.Stat(filepath.Join(s.Dir, ref)); err != nil {
+			errs = append(errs, fmt.Errorf("reference %s: %w", ref, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// IsPattern reports whether an allowed-tools entry is a glob pattern rather
+// than a single tool name
+func IsPattern(entry string) bool {
+	return strings.ContainsAny(entry, `*?[\`)
+}
@@ -0,0 +1,166 @@
+package skill
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSkill creates dir/name/SKILL.md with the given content
+func writeSkill(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name, FileName), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	s, err := Parse([]byte("---\r\nname: cnb-skill\r\ndescription: CNB 平台操作助手\r\nversion: 1.2.0\r\n" +
+		"env: [CNB_TOKEN]\r\nallowed-tools: cnb_*, execute_bash\r\nscripts:\r\n  - scripts/cnb-mcp.py\r\n" +
+		"metadata:\r\n  owner: platform\r\n---\r\n\r\n# CNB\r\n"))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if s.Name != "cnb-skill" || s.Description != "CNB 平台操作助手" || s.Version != "1.2.0" {
+		t.Errorf("Unexpected skill %+v", s)
+	}
+	if len(s.Env) != 1 || s.Env[0] != "CNB_TOKEN" {
+		t.Errorf("Unexpected env %v", s.Env)
+	}
+	if len(s.AllowedTools) != 2 || s.AllowedTools[0] != "cnb_*" || s.AllowedTools[1] != "execute_bash" {
+		t.Errorf("Expected allowed-tools split into two patterns, got %q", s.AllowedTools)
+	}
+	if len(s.Scripts) != 1 || s.Metadata["owner"] != "platform" {
+		t.Errorf("Unexpected scripts %v or metadata %v", s.Scripts, s.Metadata)
+	}
+	if s.Body != "# CNB" {
+		t.Errorf("Expected the body without frontmatter, got %q", s.Body)
+	}
+
+	tests := map[string]string{
+		"no frontmatter":    "# CNB\n",
+		"unterminated":      "---\nname: cnb-skill\n",
+		"invalid yaml":      "---\nname: [cnb\n---\n",
+		"no name":           "---\ndescription: CNB\n---\n",
+		"invalid name":      "---\nname: CNB Skill\ndescription: CNB\n---\n",
+		"no description":    "---\nname: cnb-skill\n---\n",
+		"empty frontmatter": "---\n---\n# CNB\n",
+		"unknown field":     "---\nname: cnb-skill\ndescription: CNB\nallowed_tools: [cnb_*]\n---\n",
+		"invalid version":   "---\nname: cnb-skill\ndescription: CNB\nversion: latest\n---\n",
+		"invalid env":       "---\nname: cnb-skill\ndescription: CNB\nenv: [CNB-TOKEN]\n---\n",
+		"invalid pattern":   "---\nname: cnb-skill\ndescription: CNB\nallowed-tools: [\"cnb_[\"]\n---\n",
+		"escaping script":   "---\nname: cnb-skill\ndescription: CNB\nscripts: [../../bin/sh]\n---\n",
+	}
+	for name, content := range tests {
+		if _, err := Parse([]byte(content)); err == nil {
+			t.Errorf("%s: expected Parse() to fail", name)
+		}
+	}
+}
+
+func TestAllowsTool(t *testing.T) {
+	s := &Skill{AllowedTools: []string{"cnb/*", "list_mcp_resources"}}
+	tests := []struct {
+		names []string
+		want  bool
+	}{
+		{[]string{"get_repository", "cnb/get_repository"}, true},
+		{[]string{"List_MCP_Resources"}, true},
+		{[]string{"execute_bash"}, false},
+		{[]string{"lint-bot__check", "lint-bot/check"}, false},
+	}
+	for _, tt := range tests {
+		if got := s.AllowsTool(tt.names...); got != tt.want {
+			t.Errorf("AllowsTool(%q) = %v, want %v", tt.names, got, tt.want)
+		}
+	}
+
+	if !(&Skill{}).AllowsTool("execute_bash") {
+		t.Error("Expected a skill without allowed-tools to allow every tool")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	repo, user := t.TempDir(), t.TempDir()
+	writeSkill(t, repo, "cnb-skill", "---\nname: cnb-skill\ndescription: repository copy\n---\n")
+	writeSkill(t, user, "cnb-skill", "---\nname: cnb-skill\ndescription: user copy\n---\n")
+	writeSkill(t, user, "release", "---\nname: release\ndescription: Cut releases\n---\n")
+	writeSkill(t, user, "broken", "# no frontmatter\n")
+	if err := os.Mkdir(filepath.Join(user, "notes"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Load([]string{repo, filepath.Join(repo, "missing"), user})
+	if err == nil || !strings.Contains(err.Error(), filepath.Join(user, "broken", FileName)) {
+		t.Errorf("Expected an error naming the broken skill, got %v", err)
+	}
+	if r.Len() != 2 {
+		t.Fatalf("Expected 2 skills, got %d", r.Len())
+	}
+	if all := r.All(); all[0].Name != "cnb-skill" || all[1].Name != "release" {
+		t.Errorf("Expected skills sorted by name, got %s and %s", all[0].Name, all[1].Name)
+	}
+
+	s, ok := r.Get("cnb-skill")
+	if !ok || s.Description != "repository copy" {
+		t.Errorf("Expected the first directory to win, got %+v", s)
+	}
+	if s.Path() != filepath.Join(repo, "cnb-skill", FileName) {
+		t.Errorf("Unexpected path %s", s.Path())
+	}
+}
+
+func TestCheckFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "scripts"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, mode := range map[string]os.FileMode{"run.sh": 0o755, "notes.py": 0o644} {
+		if err := os.WriteFile(filepath.Join(dir, "scripts", name), []byte("#!/bin/sh\n"), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := &Skill{Dir: dir, Scripts: []string{"scripts/run.sh"}}
+	if err := s.CheckFiles(); err != nil {
+		t.Errorf("CheckFiles() failed: %v", err)
+	}
+
+	s = &Skill{Dir: dir, Scripts: []string{"scripts/notes.py", "scripts/missing.sh"}, References: []string{"guide.md"}}
+	err := s.CheckFiles()
+	for _, want := range []string{"notes.py is not executable", "missing.sh", "reference guide.md"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected CheckFiles() to report %q, got %v", want, err)
+		}
+	}
+}
+
+func TestScaffold(t *testing.T) {
+	parent := t.TempDir()
+	dir, err := Scaffold(parent, "release-notes")
+	if err != nil {
+		t.Fatalf("Scaffold() failed: %v", err)
+	}
+
+	s, err := Read(dir)
+	if err != nil {
+		t.Fatalf("Expected the scaffolded skill to parse, got %v", err)
+	}
+	if s.Name != "release-notes" || !strings.HasPrefix(s.Body, "# Release notes") {
+		t.Errorf("Unexpected scaffolded skill %+v", s)
+	}
+	if err := s.CheckFiles(); err != nil {
+		t.Errorf("Expected the scaffolded skill to pass CheckFiles(), got %v", err)
+	}
+
+	if _, err := Scaffold(parent, "release-notes"); err == nil {
+		t.Error("Expected Scaffold() to refuse an existing directory")
+	}
+	if _, err := Scaffold(parent, "Release Notes"); err == nil {
+		t.Error("Expected Scaffold() to reject an invalid name")
+	}
+}
@@ -1,13 +1,24 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"cnb.cool/znb/learn-skills/internal/artifact"
+	"cnb.cool/znb/learn-skills/internal/audit"
 	"cnb.cool/znb/learn-skills/internal/cli"
 	"cnb.cool/znb/learn-skills/internal/config"
 	"cnb.cool/znb/learn-skills/internal/llm"
+	"cnb.cool/znb/learn-skills/internal/mcp"
+	"cnb.cool/znb/learn-skills/internal/policy"
+	"cnb.cool/znb/learn-skills/internal/sandbox"
+	"cnb.cool/znb/learn-skills/internal/session"
+	"cnb.cool/znb/learn-skills/internal/skill"
 )
 
 func main() {
@@ -18,6 +29,57 @@ func main() {
 }
 
 func run() error {
+	args := os.Args[1:]
+
+	sessionDir, err := session.DefaultDir()
+	if err != nil {
+		return err
+	}
+	sessions := session.NewStore(sessionDir)
+
+	// Subcommands that need neither the LLM nor the MCP servers
+	if len(args) > 0 && args[0] == "sessions" {
+		return cli.PrintSessions(sessions)
+	}
+	if len(args) > 0 && args[0] == "audit" {
+		path, err := config.AuditPath()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		return cli.RunAudit(filepath.Base(os.Args[0]), args[1:], path)
+	}
+	if len(args) > 0 && args[0] == "skills" {
+		dirs, err := config.SkillDirs()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		// Only skills validate connects to the MCP servers, and only then needs credentials
+		connect := func() (*mcp.Manager, error) {
+			cfg, err := config.Load()
+			if err != nil {
+				return nil, fmt.Errorf("failed to load config: %w", err)
+			}
+			return newMCPManager(cfg), nil
+		}
+		return cli.RunSkills(filepath.Base(os.Args[0]), args[1:], skillDirs(dirs), connect)
+	}
+
+	flags := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	resume := flags.String("resume", "", "continue a saved session by ID, unique ID prefix or \"last\"")
+	yes := flags.Bool("yes", false, "run write and destructive tool calls without asking")
+	readOnly := flags.Bool("read-only", false, "disable every tool that can change state (also read_only in config)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s [flags] [query...]\n       %s sessions\n       %s audit [-since 24h] [-tool name] [-repo org/repo] [-verify]\n       %s skills [list|show|validate|init]\n\nFlags:\n", flags.Name(), flags.Name(), flags.Name(), flags.Name())
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	args = flags.Args()
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -30,21 +92,71 @@ func run() error {
 		return fmt.Errorf("failed to create LLM client: %w", err)
 	}
 
-	// Load skill
-	skillPath := filepath.Join("skills", "cnb-skill", "SKILL.md")
-	skillContent, err := os.ReadFile(skillPath)
+	mcpManager := newMCPManager(cfg)
+	defer mcpManager.Close()
+
+	// Oversized tool outputs are stored here for the model to page through
+	artifactDir := cfg.ToolOutput.Dir
+	if artifactDir == "" {
+		if artifactDir, err = artifact.DefaultDir(); err != nil {
+			return err
+		}
+	}
+
+	// Write and destructive tool calls need approval
+	pol, err := policy.New(cfg.Approval.Tools)
+	if err != nil {
+		return fmt.Errorf("invalid approval settings: %w", err)
+	}
+	pol.ReadOnly = cfg.ReadOnly || *readOnly
+
+	// execute_bash commands run under the selected sandbox profile
+	profile, _ := cfg.Sandbox.ActiveProfile()
+	box, err := sandbox.New(cfg.Sandbox.Profile, profile)
+	if err != nil {
+		return err
+	}
+	if warning := box.Warning(); warning != "" {
+		fmt.Fprintf(os.Stderr, "⚠️  %s\n", warning)
+	}
+
+	// Every tool call is recorded in the audit log
+	var auditLog *audit.Log
+	if cfg.Audit.Enabled {
+		auditPath := cfg.Audit.Path
+		if auditPath == "" {
+			if auditPath, err = audit.DefaultPath(); err != nil {
+				return err
+			}
+		}
+		auditLog = audit.Open(auditPath)
+	}
+
+	// Load skills; malformed ones are reported and skipped
+	dirs := skillDirs(cfg.Skills.Dirs)
+	skills, err := skill.Load(dirs)
 	if err != nil {
-		return fmt.Errorf("failed to read skill file: %w", err)
+		fmt.Fprintf(os.Stderr, "⚠️  部分技能无法加载：\n%v\n", err)
+	}
+	if skills.Len() == 0 {
+		return fmt.Errorf("no skills found in %s", strings.Join(dirs, ", "))
 	}
 
 	// Create assistant
-	assistant := cli.NewAssistant(llmClient, string(skillContent))
+	assistant := cli.NewAssistant(cfg, llmClient, mcpManager, skills, sessions, artifact.NewStore(artifactDir), pol, box, auditLog)
 	if err := assistant.Initialize(); err != nil {
 		return fmt.Errorf("failed to initialize assistant: %w", err)
 	}
+	if *yes {
+		assistant.Approve = policy.ApproveAll
+	}
+	if *resume != "" {
+		if err := assistant.ResumeSession(*resume); err != nil {
+			return fmt.Errorf("failed to resume session: %w", err)
+		}
+	}
 
 	// Determine mode based on arguments
-	args := os.Args[1:]
 	if len(args) == 0 {
 		// Interactive mode
 		return cli.RunInteractive(assistant)
@@ -53,3 +165,42 @@ func run() error {
 		return cli.RunOneShot(assistant, args)
 	}
 }
+
+// newMCPManager registers the MCP servers: CNB keeps its tool names, others
+// are prefixed to avoid collisions
+func newMCPManager(cfg *config.Config) *mcp.Manager {
+	manager := mcp.NewManager()
+	manager.Add("cnb", "", mcp.NewClient(cfg.CNB.MCPURL, cfg.CNB.Token, cfg.CNB.Transport))
+	for _, s := range cfg.MCPServers {
+		if !s.IsEnabled() {
+			continue
+		}
+		manager.Add(s.Name, s.Prefix(), newMCPServerClient(s))
+	}
+	return manager
+}
+
+// skillDirs returns the configured skill directories, or the default search path
+func skillDirs(configured []string) []string {
+	if len(configured) == 0 {
+		return skill.DefaultDirs()
+	}
+	return configured
+}
+
+// newMCPServerClient creates the client for an additional MCP server
+func newMCPServerClient(s config.MCPServerConfig) *mcp.Client {
+	if s.Command != "" {
+		transport := mcp.NewStdioTransport(s.Command, s.Args, s.Env, s.Dir)
+		return mcp.NewClientWithTransport(s.Name, transport)
+	}
+
+	header := http.Header{}
+	for k, v := range s.Headers {
+		header.Set(k, v)
+	}
+	if s.Token != "" {
+		header.Set("Authorization", "Bearer "+s.Token)
+	}
+	return mcp.NewClientWithHeader(s.URL, header, s.Transport)
+}
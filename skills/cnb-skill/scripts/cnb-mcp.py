@@ -1,15 +1,22 @@
 #!/usr/bin/env python3
 """
 CNB MCP 调用脚本
-通过 HTTP API 调用 CNB MCP server (https://mcp.cnb.cool/mcp)
-支持从环境变量或 .env 文件读取 CNB_TOKEN
+通过 HTTP API 调用 CNB MCP server (默认 https://mcp.cnb.cool/sse)
+支持 Streamable HTTP 和旧版 HTTP+SSE 两种传输方式，也可直接调用 CNB OpenAPI
+支持从环境变量或 .env 文件读取 CNB_TOKEN、CNB_MCP_URL、CNB_MCP_TRANSPORT 和 CNB_API_BASE
 """
 
 import sys
 import json
 import os
+import urllib.error
+import urllib.parse
+import urllib.request
 from pathlib import Path
 
+# 请求超时（秒）
+TIMEOUT = 30
+
 def load_env_file():
     """
     加载 .env 文件（如果存在）
@@ -56,20 +63,35 @@ def load_env_file():
 
     return False, {}
 
+def get_setting(env_vars, key, default=None):
+    """读取配置（环境变量优先，其次 .env 文件，最后使用默认值）"""
+    return os.getenv(key) or env_vars.get(key) or default
+
+def fail(error, **extra):
+    """输出 JSON 格式的错误并退出"""
+    print(json.dumps({"error": error, **extra}, ensure_ascii=False), file=sys.stderr)
+    sys.exit(1)
+
+def get_token(env_vars):
+    """获取 CNB token（优先从系统环境变量读取，其次 .env 文件）"""
+    token = get_setting(env_vars, 'CNB_TOKEN')
+    if not token:
+        fail("CNB_TOKEN 未设置", hint="请设置 CNB_TOKEN 环境变量或在 .env 文件中配置 CNB_TOKEN=your_token")
+    return token
+
+def use_sse(mcp_url, transport):
+    """判断是否使用旧版 HTTP+SSE：显式指定 sse，或 auto 时地址以 /sse 结尾"""
+    if transport == 'sse':
+        return True
+    if transport == 'streamable-http':
+        return False
+    return urllib.parse.urlparse(mcp_url).path.rstrip('/').endswith('/sse')
+
 def call_mcp(method, params=None):
     """调用 MCP server"""
     # 先尝试加载 .env 文件
     _, env_vars = load_env_file()
-
-    # 获取 CNB token（优先从 .env 文件读取,如果为空则从系统环境变量获取）
-    token = env_vars.get('CNB_TOKEN') or os.getenv('CNB_TOKEN')
-
-    if not token:
-        print(json.dumps({
-            "error": "CNB_TOKEN 未设置",
-            "hint": "请设置 CNB_TOKEN 环境变量或在 .env 文件中配置 CNB_TOKEN=your_token"
-        }, ensure_ascii=False), file=sys.stderr)
-        sys.exit(1)
+    token = get_token(env_vars)
 
     # 构建 JSON-RPC 请求
     request = {
@@ -80,69 +102,156 @@ def call_mcp(method, params=None):
     if params:
         request["params"] = params
 
-    # MCP endpoint 地址（固定值,一般不需要修改）
-    mcp_url = 'https://mcp.cnb.cool/mcp'
+    # MCP endpoint 地址，默认与助手的 cnb.mcp_url 一致
+    mcp_url = get_setting(env_vars, 'CNB_MCP_URL', 'https://mcp.cnb.cool/sse')
+    transport = get_setting(env_vars, 'CNB_MCP_TRANSPORT', 'auto')
 
-    import subprocess
+    try:
+        if use_sse(mcp_url, transport):
+            response = call_sse(mcp_url, token, request)
+        else:
+            response = call_streamable_http(mcp_url, token, request)
+    except urllib.error.HTTPError as e:
+        fail(f"MCP 请求失败: HTTP {e.code}", output=e.read().decode('utf-8', 'replace'))
+    except (urllib.error.URLError, TimeoutError, OSError) as e:
+        fail(f"无法连接 MCP endpoint {mcp_url}: {e}")
+
+    # 检查 JSON-RPC 错误
+    if "error" in response:
+        fail(response["error"])
+
+    return response.get('result', response)
+
+def call_streamable_http(mcp_url, token, request):
+    """通过 Streamable HTTP 发送一次 POST，响应可能是 JSON 或 SSE 格式"""
+    req = urllib.request.Request(
+        mcp_url,
+        data=json.dumps(request).encode('utf-8'),
+        headers={
+            'Content-Type': 'application/json',
+            'Accept': 'application/json, text/event-stream',
+            'Authorization': f'Bearer {token}',
+        },
+        method='POST',
+    )
+    with urllib.request.urlopen(req, timeout=TIMEOUT) as resp:
+        output = resp.read().decode('utf-8').strip()
 
     try:
-        result = subprocess.run(
-            [
-                'curl', '-s', '-X', 'POST',
-                mcp_url,
-                '-H', 'Content-Type: application/json',
-                '-H', 'Accept: application/json, text/event-stream',
-                '-H', f'Authorization: Bearer {token}',
-                '-d', json.dumps(request)
-            ],
-            capture_output=True,
-            text=True,
-            timeout=30
-        )
-
-        if result.returncode != 0:
-            print(json.dumps({"error": f"curl 执行失败: {result.stderr}"}), file=sys.stderr)
-            sys.exit(1)
+        # 检查是否是 SSE 格式 (event: message\ndata: {...})
+        if output.startswith('event:') or output.startswith('data:'):
+            for line in output.split('\n'):
+                if line.startswith('data:'):
+                    return json.loads(line[5:].strip())  # 移除 "data:" 前缀
+            fail("SSE 格式中未找到 data 行")
+        # 普通 JSON 响应
+        return json.loads(output)
+    except json.JSONDecodeError as e:
+        fail(f"JSON 解析失败: {str(e)}", output=output)
 
-        # 解析响应（可能是 SSE 格式）
-        try:
-            output = result.stdout.strip()
-
-            # 检查是否是 SSE 格式 (event: message\ndata: {...})
-            if output.startswith('event:'):
-                # 解析 SSE 格式
-                lines = output.split('\n')
-                for line in lines:
-                    if line.startswith('data:'):
-                        json_str = line[5:].strip()  # 移除 "data:" 前缀
-                        response = json.loads(json_str)
-                        break
-                else:
-                    print(json.dumps({"error": "SSE 格式中未找到 data 行"}), file=sys.stderr)
-                    sys.exit(1)
-            else:
-                # 普通 JSON 响应
-                response = json.loads(output)
-
-            # 检查 JSON-RPC 错误
-            if "error" in response:
-                print(json.dumps({"error": response["error"]}), file=sys.stderr)
-                sys.exit(1)
-
-            return response.get('result', response)
-        except json.JSONDecodeError as e:
-            print(json.dumps({
-                "error": f"JSON 解析失败: {str(e)}",
-                "output": result.stdout
-            }), file=sys.stderr)
-            sys.exit(1)
+def read_events(stream):
+    """逐个读取 SSE 事件，返回 (event, data)"""
+    event, data = '', []
+    for raw in stream:
+        line = raw.decode('utf-8').rstrip('\r\n')
+        if line == '':
+            if data:
+                yield event, '\n'.join(data)
+            event, data = '', []
+        elif line.startswith('event:'):
+            event = line[6:].strip()
+        elif line.startswith('data:'):
+            data.append(line[5:].lstrip())
 
-    except subprocess.TimeoutExpired:
-        print(json.dumps({"error": "MCP 调用超时"}), file=sys.stderr)
-        sys.exit(1)
-    except Exception as e:
-        print(json.dumps({"error": str(e)}), file=sys.stderr)
-        sys.exit(1)
+def call_sse(mcp_url, token, request):
+    """
+    通过旧版 HTTP+SSE 调用：GET 打开事件流，服务器用 endpoint 事件告知 POST 地址，
+    请求以 POST 发送，响应从事件流返回。先完成 initialize 握手再发送请求。
+    """
+    headers = {'Authorization': f'Bearer {token}'}
+    stream_req = urllib.request.Request(
+        mcp_url, headers={**headers, 'Accept': 'text/event-stream', 'Cache-Control': 'no-cache'})
+
+    with urllib.request.urlopen(stream_req, timeout=TIMEOUT) as stream:
+        events = read_events(stream)
+
+        endpoint = None
+        for event, data in events:
+            if event == 'endpoint':
+                endpoint = urllib.parse.urljoin(mcp_url, data.strip())
+                break
+        if not endpoint:
+            fail("事件流中未收到 endpoint 事件", hint="请检查 CNB_MCP_URL 是否为 HTTP+SSE 地址，或设置 CNB_MCP_TRANSPORT")
+
+        def post(message):
+            req = urllib.request.Request(
+                endpoint,
+                data=json.dumps(message).encode('utf-8'),
+                headers={**headers, 'Content-Type': 'application/json'},
+                method='POST',
+            )
+            with urllib.request.urlopen(req, timeout=TIMEOUT):
+                pass
+
+        def wait_for(request_id):
+            for event, data in events:
+                if event not in ('', 'message'):
+                    continue
+                try:
+                    message = json.loads(data)
+                except json.JSONDecodeError:
+                    continue
+                if message.get('id') == request_id and 'method' not in message:
+                    return message
+            fail("事件流在收到响应前关闭")
+
+        post({
+            "jsonrpc": "2.0",
+            "id": 0,
+            "method": "initialize",
+            "params": {
+                "protocolVersion": "2024-11-05",
+                "capabilities": {},
+                "clientInfo": {"name": "cnb-mcp.py", "version": "1.0"},
+            },
+        })
+        init = wait_for(0)
+        if "error" in init:
+            return init
+        post({"jsonrpc": "2.0", "method": "notifications/initialized"})
+
+        post(request)
+        return wait_for(request["id"])
+
+def call_api(method, path, body=None):
+    """调用 CNB OpenAPI，地址取自 CNB_API_BASE"""
+    _, env_vars = load_env_file()
+    token = get_token(env_vars)
+    api_base = get_setting(env_vars, 'CNB_API_BASE', 'https://api.cnb.cool')
+
+    url = api_base.rstrip('/') + '/' + path.lstrip('/')
+    req = urllib.request.Request(
+        url,
+        data=body.encode('utf-8') if body else None,
+        headers={
+            'Accept': 'application/json',
+            'Content-Type': 'application/json',
+            'Authorization': f'Bearer {token}',
+        },
+        method=method.upper(),
+    )
+    try:
+        with urllib.request.urlopen(req, timeout=TIMEOUT) as resp:
+            output = resp.read().decode('utf-8')
+    except urllib.error.HTTPError as e:
+        fail(f"OpenAPI 请求失败: HTTP {e.code}", output=e.read().decode('utf-8', 'replace'))
+    except (urllib.error.URLError, TimeoutError, OSError) as e:
+        fail(f"无法连接 CNB OpenAPI {url}: {e}")
+
+    try:
+        return json.loads(output) if output else None
+    except json.JSONDecodeError:
+        return output
 
 def main():
     if len(sys.argv) < 2:
@@ -150,6 +259,7 @@ def main():
         print("支持的操作:")
         print("  list-tools          - 列出所有可用工具")
         print("  call <tool> <args>  - 调用指定工具")
+        print("  api <method> <path> [json] - 调用 CNB OpenAPI（CNB_API_BASE）")
         sys.exit(1)
 
     operation = sys.argv[1]
@@ -182,6 +292,15 @@ def main():
         })
         print(json.dumps(result, ensure_ascii=False, indent=2))
 
+    elif operation == "api":
+        if len(sys.argv) < 4:
+            print("错误: 需要指定 HTTP 方法和路径，如 api GET /user")
+            sys.exit(1)
+
+        body = sys.argv[4] if len(sys.argv) > 4 else None
+        result = call_api(sys.argv[2], sys.argv[3], body)
+        print(json.dumps(result, ensure_ascii=False, indent=2))
+
     else:
         print(f"未知操作: {operation}")
         sys.exit(1)